@@ -39,17 +39,66 @@ type Builder struct {
 
 // New creates a new pipeline builder.
 func New() *Builder {
+	return NewRemote("")
+}
+
+// NewRemote creates a new pipeline builder for a repository
+// cloned from the remote url. The remote url is used to name
+// the docker image if the repository does not include the
+// git configuration.
+func NewRemote(remote string) *Builder {
+	return newBuilder(ConfigureDockerRemote(remote))
+}
+
+// NewMultiPlatform creates a new pipeline builder for a
+// repository cloned from the remote url, which configures
+// multi-platform docker builds if the base images publish
+// arm64 variants.
+func NewMultiPlatform(remote string) *Builder {
+	return newBuilder(ConfigureDockerMultiPlatform(remote))
+}
+
+// helper function creates a new pipeline builder with the
+// default rules and the given docker rule.
+func newBuilder(docker Rule) *Builder {
 	return &Builder{
 		rules: []Rule{
 			ConfigurePlatform,
-			ConfigureGo,
-			ConfigureNode,
-			ConfigurePython,
-			ConfigureRails,
-			ConfigureRuby,
-			ConfigureRust,
-			ConfigureSwift,
-			ConfigureDocker,
+			ConfigureBazel,
+			ConfigureTaskRunner,
+
+			// language rules are skipped if the build and
+			// test commands are wrapped in task runner
+			// targets.
+			DeferToTaskRunner(
+				ConfigureGo,
+				ConfigureNode,
+				ConfigurePython,
+				ConfigureRails,
+				ConfigureRuby,
+				ConfigureRust,
+				ConfigureSwift,
+				ConfigureJava,
+				ConfigureScala,
+				ConfigureClojure,
+				ConfigureDotnet,
+				ConfigurePHP,
+				ConfigureElixir,
+				ConfigureDart,
+				ConfigureHaskell,
+				ConfigureOCaml,
+				ConfigureZig,
+				ConfigureCpp,
+			),
+
+			ConfigureTerraform,
+			ConfigurePulumi,
+			ConfigureCdk,
+			ConfigureKubernetes,
+			ConfigureSite,
+			ConfigureProtobuf,
+			ConfigureOpenAPI,
+			docker,
 
 			// default rule should always be last in the list
 			ConfigureDefault,
@@ -120,3 +169,17 @@ func createScriptStep(image, name, command string) *spec.Step {
 
 	return step
 }
+
+// helper function to create a background service step.
+func createServiceStep(image, name string, env map[string]string) *spec.Step {
+	run := new(spec.StepRun)
+	run.Env = env
+	run.Container = new(spec.Container)
+	run.Container.Image = image
+
+	step := new(spec.Step)
+	step.Name = name
+	step.Background = run
+
+	return step
+}
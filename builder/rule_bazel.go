@@ -0,0 +1,101 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"bytes"
+	"io/fs"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// ConfigureBazel configures a Bazel or Buck step. Bazel and
+// Buck build every language in the repository, so all
+// remaining rules are skipped to prevent conflicting
+// language-specific steps.
+func ConfigureBazel(fsys fs.FS, pipeline *spec.Pipeline) error {
+	switch {
+	case exists(fsys, "MODULE.bazel"),
+		exists(fsys, "WORKSPACE"),
+		exists(fsys, "WORKSPACE.bazel"),
+		exists(fsys, ".bazelversion"):
+		configureBazel(fsys, pipeline)
+		return SkipAll
+	case exists(fsys, ".buckconfig"),
+		exists(fsys, "BUCK"):
+		configureBuck(fsys, pipeline)
+		return SkipAll
+	}
+	return nil
+}
+
+// helper function configures the bazel steps.
+func configureBazel(fsys fs.FS, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	// check if we should use a container-based
+	// execution environment. the bazel image runs
+	// bazelisk, which downloads the bazel version
+	// pinned in the .bazelversion file.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "gcr.io/bazel-public/bazel"
+	}
+
+	// use the remote cache if configured in the .bazelrc
+	// file, otherwise use a disk cache in the workspace
+	// directory that can be shared between steps.
+	flags := " --disk_cache=$PWD/.cache/bazel"
+	if bazelrc, _ := read(fsys, ".bazelrc"); bytes.Contains(bazelrc, []byte("--remote_cache")) {
+		flags = ""
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"bazel_build",
+		"bazel build"+flags+" //...",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"bazel_test",
+		"bazel test"+flags+" //...",
+	))
+}
+
+// helper function configures the buck2 steps.
+func configureBuck(fsys fs.FS, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	// check if we should use a container-based
+	// execution environment. there is no official buck2
+	// image, so the prebuilt binary is installed in each
+	// step.
+	var image, setup string
+	if isContainerRuntime(pipeline) {
+		image = "debian"
+		setup = "apt-get update && apt-get install -y curl zstd && " +
+			"curl -fsSL https://github.com/facebook/buck2/releases/download/latest/buck2-x86_64-unknown-linux-gnu.zst" +
+			" | zstd -d -o /usr/local/bin/buck2 && chmod +x /usr/local/bin/buck2 && "
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"buck2_build",
+		setup+"buck2 build //...",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"buck2_test",
+		setup+"buck2 test //...",
+	))
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// ConfigureCdk configures an AWS CDK synth step.
+func ConfigureCdk(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	// parse the cdk.json file
+	cdk := new(cdkJson)
+	if err := unmarshal(fsys, "cdk.json", cdk); err != nil {
+		return nil
+	}
+
+	// python applications are synthesized using the
+	// python image. the cdk cli is installed with npm,
+	// since it is not included in the image.
+	if strings.HasPrefix(cdk.App, "python") {
+		var image, setup string
+		if isContainerRuntime(pipeline) {
+			pyproject, _ := readToml(fsys, "pyproject.toml")
+			image = "python:" + pythonVersion(fsys, pyproject)
+			setup = "apt-get update && apt-get install -y nodejs npm && "
+		}
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"cdk_synth",
+			setup+"pip install -r requirements.txt && npx aws-cdk synth",
+		))
+		return nil
+	}
+
+	// parse the package.json file, if exists.
+	json := new(packageJson)
+	unmarshal(fsys, "package.json", json)
+
+	manager := nodePackageManager(fsys, json)
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "node"
+		if v := nodeVersion(fsys, json); v != "" {
+			image = "node:" + v
+		}
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"cdk_synth",
+		nodeInstall(fsys, manager, json)+" && "+nodeExec(manager)+" cdk synth",
+	))
+
+	return nil
+}
+
+// represents the cdk.json file format.
+type cdkJson struct {
+	App string `json:"app"`
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"regexp"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expression to detect the test alias in the
+// deps.edn file.
+var depsTestAlias = regexp.MustCompile(`:test\s*\{`)
+
+// ConfigureClojure configures a Clojure step for Leiningen
+// and Clojure CLI (deps.edn) projects.
+func ConfigureClojure(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	var tool string
+	switch {
+	case exists(fsys, "project.clj"):
+		tool = "lein"
+	case exists(fsys, "deps.edn"):
+		tool = "tools-deps"
+	default:
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment. the clojure image is
+	// tagged by jdk version and build tool.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "clojure:" + tool
+		if v := jdkVersion(fsys); v != "" {
+			image = "clojure:temurin-" + v + "-" + tool
+		}
+	}
+
+	if tool == "lein" {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"lein_compile",
+			"lein compile",
+		))
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"lein_test",
+			"lein test",
+		))
+		return nil
+	}
+
+	// download the dependencies, which also verifies
+	// the deps.edn file.
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"clojure_deps",
+		"clojure -P",
+	))
+
+	// add the test step if the test alias is defined.
+	deps, _ := read(fsys, "deps.edn")
+	if depsTestAlias.Match(deps) {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"clojure_test",
+			"clojure -X:test",
+		))
+	}
+	return nil
+}
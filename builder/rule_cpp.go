@@ -0,0 +1,242 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expressions to extract the dependencies from the
+// CMakeLists.txt and meson.build files.
+var (
+	cmakePackage  = regexp.MustCompile(`(?i)find_package\s*\(\s*([\w.-]+)`)
+	mesonPackage  = regexp.MustCompile(`dependency\s*\(\s*'([\w.+-]+)'`)
+	makefileCheck = regexp.MustCompile(`(?m)^check\s*:`)
+)
+
+// cmake packages and meson dependencies mapped to the debian
+// packages that provide them. Packages provided by the
+// compiler image (e.g. Threads) map to an empty string.
+var cppPackages = map[string]string{
+	// cmake packages
+	"Boost":         "libboost-all-dev",
+	"BZip2":         "libbz2-dev",
+	"Catch2":        "catch2",
+	"CURL":          "libcurl4-openssl-dev",
+	"Eigen3":        "libeigen3-dev",
+	"fmt":           "libfmt-dev",
+	"GTest":         "libgtest-dev",
+	"JPEG":          "libjpeg-dev",
+	"LibXml2":       "libxml2-dev",
+	"nlohmann_json": "nlohmann-json3-dev",
+	"OpenMP":        "",
+	"OpenSSL":       "libssl-dev",
+	"PkgConfig":     "pkg-config",
+	"PNG":           "libpng-dev",
+	"PostgreSQL":    "libpq-dev",
+	"Protobuf":      "libprotobuf-dev protobuf-compiler",
+	"spdlog":        "libspdlog-dev",
+	"SQLite3":       "libsqlite3-dev",
+	"Threads":       "",
+	"yaml-cpp":      "libyaml-cpp-dev",
+	"ZLIB":          "zlib1g-dev",
+
+	// meson dependencies
+	"glib-2.0":   "libglib2.0-dev",
+	"gtest":      "libgtest-dev",
+	"libcurl":    "libcurl4-openssl-dev",
+	"libpq":      "libpq-dev",
+	"libxml-2.0": "libxml2-dev",
+	"openssl":    "libssl-dev",
+	"sqlite3":    "libsqlite3-dev",
+	"threads":    "",
+	"zlib":       "zlib1g-dev",
+}
+
+// ConfigureCpp configures a C or C++ step for CMake, Meson,
+// Autotools and Makefile projects.
+func ConfigureCpp(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "gcc"
+	}
+
+	// helper function returns the command to install the
+	// build tools and the dependencies inferred from the
+	// build file. Packages must be installed in each step
+	// since the container is not shared between steps.
+	install := func(re *regexp.Regexp, name string, tools ...string) string {
+		if image == "" {
+			return ""
+		}
+		return cppInstall(fsys, re, name, tools...)
+	}
+
+	switch {
+	case exists(fsys, "CMakeLists.txt"):
+		setup := install(cmakePackage, "CMakeLists.txt", "cmake")
+		configure, build, test := cmakeCommands(fsys)
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"cmake_build",
+			setup+configure+" && "+build,
+		))
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"ctest",
+			setup+test,
+		))
+
+	case exists(fsys, "meson.build"):
+		setup := install(mesonPackage, "meson.build", "meson", "ninja-build", "pkg-config")
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"meson_build",
+			setup+"meson setup build && meson compile -C build",
+		))
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"meson_test",
+			setup+"meson test -C build",
+		))
+
+	case exists(fsys, "configure.ac"):
+		setup := install(nil, "", "autoconf", "automake", "libtool", "pkg-config")
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"autotools_build",
+			setup+"autoreconf -fi && ./configure && make",
+		))
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"make_check",
+			"make check",
+		))
+
+	case exists(fsys, "Makefile") && isCpp(fsys):
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"make",
+			"make",
+		))
+		makefile, _ := read(fsys, "Makefile")
+		if makefileCheck.Match(makefile) {
+			stage.Steps = append(stage.Steps, createScriptStep(image,
+				"make_check",
+				"make check",
+			))
+		}
+	}
+
+	return nil
+}
+
+// helper function returns the cmake configure, build and
+// test commands, using the first configure, build and test
+// presets in the CMakePresets.json file, if exists.
+func cmakeCommands(fsys fs.FS) (configure, build, test string) {
+	configure = "cmake -B build -DCMAKE_BUILD_TYPE=Release"
+	build = "cmake --build build"
+	test = "ctest --test-dir build --output-on-failure"
+
+	presets := new(cmakePresets)
+	if err := unmarshal(fsys, "CMakePresets.json", presets); err != nil {
+		return
+	}
+	for _, preset := range presets.ConfigurePresets {
+		if preset.Hidden || preset.Name == "" {
+			continue
+		}
+		configure = "cmake --preset " + quote(preset.Name)
+		if dir := preset.BinaryDir; dir != "" {
+			dir = strings.ReplaceAll(dir, "${sourceDir}/", "")
+			dir = strings.ReplaceAll(dir, "${presetName}", preset.Name)
+			build = "cmake --build " + quote(dir)
+			test = "ctest --test-dir " + quote(dir) + " --output-on-failure"
+		}
+		break
+	}
+	for _, preset := range presets.BuildPresets {
+		if !preset.Hidden && preset.Name != "" {
+			build = "cmake --build --preset " + quote(preset.Name)
+			break
+		}
+	}
+	for _, preset := range presets.TestPresets {
+		if !preset.Hidden && preset.Name != "" {
+			test = "ctest --preset " + quote(preset.Name) + " --output-on-failure"
+			break
+		}
+	}
+	return
+}
+
+// helper function returns the command to install the build
+// tools and the dependencies inferred from the build file.
+func cppInstall(fsys fs.FS, re *regexp.Regexp, name string, tools ...string) string {
+	data, _ := read(fsys, name)
+	return "apt-get update && apt-get install -y " +
+		strings.Join(append(tools, cppDependencies(re, data)...), " ") + " && "
+}
+
+// helper function returns the debian packages for the
+// dependencies in the build file that can be mapped.
+func cppDependencies(re *regexp.Regexp, data []byte) []string {
+	if re == nil {
+		return nil
+	}
+	set := map[string]bool{}
+	for _, match := range re.FindAllSubmatch(data, -1) {
+		if pkg := cppPackages[string(match[1])]; pkg != "" {
+			set[pkg] = true
+		}
+	}
+	var packages []string
+	for pkg := range set {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// helper function returns true if the project contains c
+// or c++ source files in the root or a subdirectory.
+func isCpp(fsys fs.FS) bool {
+	for _, ext := range []string{"c", "cc", "cpp", "cxx"} {
+		if match(fsys, "*."+ext) || match(fsys, "*/*."+ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// represents the CMakePresets.json file format.
+type cmakePresets struct {
+	ConfigurePresets []struct {
+		Name      string `json:"name"`
+		Hidden    bool   `json:"hidden"`
+		BinaryDir string `json:"binaryDir"`
+	} `json:"configurePresets"`
+	BuildPresets []struct {
+		Name   string `json:"name"`
+		Hidden bool   `json:"hidden"`
+	} `json:"buildPresets"`
+	TestPresets []struct {
+		Name   string `json:"name"`
+		Hidden bool   `json:"hidden"`
+	} `json:"testPresets"`
+}
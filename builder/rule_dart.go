@@ -0,0 +1,171 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// ConfigureDart configures a Dart or Flutter step.
+func ConfigureDart(fsys fs.FS, pipeline *spec.Pipeline) error {
+	// check for the pubspec.yaml file.
+	if !exists(fsys, "pubspec.yaml") {
+		return nil
+	}
+
+	// parse the pubspec.yaml file
+	pubspec := new(pubspecYaml)
+	if err := unmarshalYaml(fsys, "pubspec.yaml", pubspec); err != nil {
+		return nil
+	}
+
+	if pubspec.isFlutter() {
+		configureFlutter(fsys, pipeline)
+	} else {
+		configureDart(pubspec, pipeline)
+	}
+	return nil
+}
+
+// helper function configures the dart steps.
+func configureDart(pubspec *pubspecYaml, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "dart"
+		if v := minorVersion(pubspec.Environment["sdk"]); v != "" {
+			image = "dart:" + v
+		}
+	}
+
+	steps := []*spec.Step{
+		createScriptStep(image,
+			"dart_pub_get",
+			"dart pub get",
+		),
+		createScriptStep(image,
+			"dart_analyze",
+			"dart analyze",
+		),
+		createScriptStep(image,
+			"dart_test",
+			"dart test",
+		),
+	}
+	configurePubCache(steps)
+	stage.Steps = append(stage.Steps, steps...)
+}
+
+// helper function configures the flutter steps.
+func configureFlutter(fsys fs.FS, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	// check if we should use a container-based
+	// execution environment. there is no official flutter
+	// image, so the cirruslabs image is used, which also
+	// includes the android sdk.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "ghcr.io/cirruslabs/flutter:" + flutterVersion(fsys)
+	}
+
+	steps := []*spec.Step{
+		createScriptStep(image,
+			"flutter_pub_get",
+			"flutter pub get",
+		),
+		createScriptStep(image,
+			"flutter_analyze",
+			"flutter analyze",
+		),
+		createScriptStep(image,
+			"flutter_test",
+			"flutter test",
+		),
+	}
+
+	// add the build steps for the android and web
+	// targets, if configured.
+	if exists(fsys, "android") {
+		steps = append(steps, createScriptStep(image,
+			"flutter_build_apk",
+			"flutter build apk --debug",
+		))
+	}
+	if exists(fsys, "web") {
+		steps = append(steps, createScriptStep(image,
+			"flutter_build_web",
+			"flutter build web",
+		))
+	}
+
+	configurePubCache(steps)
+	stage.Steps = append(stage.Steps, steps...)
+}
+
+// helper function configures the steps to download the
+// dependencies to the workspace, since the pub cache in
+// the home directory is not shared between steps.
+func configurePubCache(steps []*spec.Step) {
+	for _, step := range steps {
+		step.Run.Env = map[string]string{
+			"PUB_CACHE": ".pub-cache",
+		}
+	}
+}
+
+// helper function returns the flutter version from the
+// .fvmrc or .tool-versions file, defaulting to the stable
+// channel.
+func flutterVersion(fsys fs.FS) string {
+	fvmrc := struct {
+		Flutter string `json:"flutter"`
+	}{}
+	unmarshal(fsys, ".fvmrc", &fvmrc)
+
+	for _, v := range []string{
+		fvmrc.Flutter,
+		toolVersion(fsys, "flutter"),
+	} {
+		// the asdf version includes the channel suffix
+		// (e.g. 3.16.9-stable), which is not part of
+		// the image tag.
+		v = strings.TrimSuffix(strings.TrimSpace(v), "-stable")
+		if version.MatchString(v) && !strings.Contains(v, "-") {
+			return v
+		}
+	}
+	return "stable"
+}
+
+// represents the pubspec.yaml file format.
+type pubspecYaml struct {
+	Name         string                 `json:"name"`
+	Environment  map[string]string      `json:"environment"`
+	Dependencies map[string]interface{} `json:"dependencies"`
+}
+
+// isFlutter returns true if the package depends on the
+// flutter sdk.
+func (p *pubspecYaml) isFlutter() bool {
+	dep, ok := p.Dependencies["flutter"].(map[string]interface{})
+	return ok && dep["sdk"] == "flutter"
+}
@@ -16,54 +16,449 @@ package builder
 
 import (
 	"io/fs"
+	"path"
+	"regexp"
+	"strings"
 
 	spec "github.com/bradrydzewski/spec/yaml"
 )
 
-// ConfigureDocker configures a Docker step.
+// regular expression to extract the origin url from the
+// .git/config file.
+var gitOrigin = regexp.MustCompile(`(?s)\[remote "origin"\][^\[]*?\burl\s*=\s*(\S+)`)
+
+// well-known build stages that are built separately using
+// the stage as the build target.
+var dockerTargets = map[string]bool{
+	"check": true,
+	"lint":  true,
+	"test":  true,
+	"tests": true,
+}
+
+// predefined build arguments, which do not need to be
+// provided by the pipeline.
+var dockerBuiltinArgs = map[string]bool{
+	"BUILDARCH":      true,
+	"BUILDOS":        true,
+	"BUILDPLATFORM":  true,
+	"BUILDVARIANT":   true,
+	"TARGETARCH":     true,
+	"TARGETOS":       true,
+	"TARGETPLATFORM": true,
+	"TARGETVARIANT":  true,
+	"HTTP_PROXY":     true,
+	"HTTPS_PROXY":    true,
+	"FTP_PROXY":      true,
+	"NO_PROXY":       true,
+	"ALL_PROXY":      true,
+	"http_proxy":     true,
+	"https_proxy":    true,
+	"ftp_proxy":      true,
+	"no_proxy":       true,
+	"all_proxy":      true,
+}
+
+// well-known base images that publish arm64 variants.
+var dockerMultiArch = map[string]bool{
+	"alpine":                            true,
+	"amazoncorretto":                    true,
+	"busybox":                           true,
+	"debian":                            true,
+	"eclipse-temurin":                   true,
+	"elixir":                            true,
+	"erlang":                            true,
+	"gcc":                               true,
+	"gcr.io/distroless/base":            true,
+	"gcr.io/distroless/base-debian12":   true,
+	"gcr.io/distroless/cc":              true,
+	"gcr.io/distroless/cc-debian12":     true,
+	"gcr.io/distroless/static":          true,
+	"gcr.io/distroless/static-debian12": true,
+	"golang":                            true,
+	"httpd":                             true,
+	"maven":                             true,
+	"mcr.microsoft.com/dotnet/aspnet":   true,
+	"mcr.microsoft.com/dotnet/runtime":  true,
+	"mcr.microsoft.com/dotnet/sdk":      true,
+	"nginx":                             true,
+	"node":                              true,
+	"openjdk":                           true,
+	"php":                               true,
+	"postgres":                          true,
+	"python":                            true,
+	"redis":                             true,
+	"ruby":                              true,
+	"rust":                              true,
+	"scratch":                           true,
+	"ubuntu":                            true,
+}
+
+// ConfigureDocker configures a Docker step for each
+// Dockerfile in the repository.
 func ConfigureDocker(fsys fs.FS, pipeline *spec.Pipeline) error {
+	return configureDocker(fsys, pipeline, "", false)
+}
+
+// ConfigureDockerRemote returns a rule that configures a
+// Docker step for each Dockerfile in the repository. The
+// remote url is used to name the image if the repository
+// does not include the git configuration.
+func ConfigureDockerRemote(remote string) Rule {
+	return func(fsys fs.FS, pipeline *spec.Pipeline) error {
+		return configureDocker(fsys, pipeline, remote, false)
+	}
+}
+
+// ConfigureDockerMultiPlatform returns a rule that configures
+// a Docker step for each Dockerfile in the repository, and a
+// multi-platform build if all base images publish arm64
+// variants. The remote url is used to name the image if the
+// repository does not include the git configuration.
+func ConfigureDockerMultiPlatform(remote string) Rule {
+	return func(fsys fs.FS, pipeline *spec.Pipeline) error {
+		return configureDocker(fsys, pipeline, remote, true)
+	}
+}
+
+// helper function configures the docker steps.
+func configureDocker(fsys fs.FS, pipeline *spec.Pipeline, remote string, multiplatform bool) error {
 	stage := pipeline.Stages[0]
 
-	// check if a Dockerfile exists
-	// TODO check subdirectories with glob
-	if !exists(fsys, "Dockerfile") {
+	// check for Dockerfiles in the repository
+	dockerfiles := dockerfiles(fsys)
+	if len(dockerfiles) == 0 {
 		return nil
 	}
 
-	// // check if we should use a container-based
-	// // execution environment.
-	// useImage := isContainerRuntime(pipeline)
+	// use the repository name from the origin url as the
+	// image name, if possible.
+	repo := dockerRepo(gitRemote(fsys))
+	if repo == "" {
+		repo = dockerRepo(remote)
+	}
+	if repo == "" {
+		repo = "hello/world" // dummy name
+	}
+
+	names := map[string]bool{}
+	for _, dockerfile := range dockerfiles {
+		// the step name must be unique, which is not the
+		// case for Dockerfiles and Containerfiles in the
+		// same directory.
+		name := dockerName(dockerfile)
+		if names[name] {
+			name = slug(dockerfile)
+		}
+		names[name] = true
 
-	// add the docker build step
-	{
-		repo := "hello/world" // dummy name
-		// TODO parse the .git/config and get the remote orign
-		// url. extract the repository name from the url and use
-		// this as the image name, if possible.
+		data, _ := read(fsys, dockerfile)
+		parsed := parseDockerfile(data)
+		context := dockerContext(fsys, dockerfile, parsed)
+
+		prefix, check := "docker_build", "dockerignore_check"
+		if name != "" {
+			prefix, check = prefix+"_"+name, check+"_"+name
+		}
 
-		tmpl := new(spec.StepTemplate)
-		tmpl.Uses = "docker"
-		tmpl.With = map[string]interface{}{
-			"tags":    "latest",
-			"repo":    repo,
-			"dry_run": true,
+		// warn about the files copied into the image that
+		// are excluded from the build context, which would
+		// fail the build. the pipeline is not failed, since
+		// the ignore file matching is approximate.
+		if ignored := dockerIgnored(fsys, dockerfile, context, parsed); len(ignored) != 0 {
+			var image string
+			if isContainerRuntime(pipeline) {
+				image = "alpine"
+			}
+			stage.Steps = append(stage.Steps, createScriptStep(image,
+				check,
+				"echo "+quote("warning: "+dockerfile+" copies files excluded by .dockerignore: "+strings.Join(ignored, ", ")),
+			))
 		}
 
-		// if useImage {
-		// 	script.Image = "plugins/docker"
-		// 	script.Privileged = true
-		// } else {
-		// 	// TODO we should eventually use the container-less
-		// 	// version of the plugin here
-		// 	script.Image = "plugins/docker"
-		// }
+		// build the well-known stages (e.g. test) as separate
+		// steps, followed by the final image.
+		for _, target := range append(parsed.targets(), "") {
+			tmpl := new(spec.StepTemplate)
+			tmpl.Uses = "docker"
+			tmpl.With = map[string]interface{}{
+				"tags":    "latest",
+				"repo":    repo,
+				"dry_run": true,
+			}
 
-		step := new(spec.Step)
-		step.Name = "docker_build"
-		step.Template = tmpl
+			// images built from Dockerfiles other than the
+			// root Dockerfile are suffixed with the
+			// Dockerfile name.
+			if name != "" {
+				tmpl.With["repo"] = repo + "-" + strings.ReplaceAll(name, "_", "-")
+			}
+			if dockerfile != "Dockerfile" {
+				tmpl.With["dockerfile"] = dockerfile
+				tmpl.With["context"] = context
+			}
 
-		stage.Steps = append(stage.Steps, step)
+			// build arguments without a default value must
+			// be provided by the pipeline, and are read from
+			// the environment.
+			if len(parsed.args) != 0 {
+				tmpl.With["build_args_from_env"] = parsed.args
+			}
+
+			// build a multi-platform image, if enabled and
+			// all base images publish arm64 variants.
+			if multiplatform && parsed.isMultiArch() {
+				tmpl.With["platforms"] = "linux/amd64,linux/arm64"
+			}
+
+			step := new(spec.Step)
+			step.Name = prefix
+			if target != "" {
+				tmpl.With["target"] = target
+				step.Name = prefix + "_" + slug(target)
+			}
+			step.Template = tmpl
+
+			stage.Steps = append(stage.Steps, step)
+		}
 	}
 
 	return nil
 }
+
+// helper function returns the Dockerfiles and Containerfiles
+// in the repository, excluding the Dockerfile-specific ignore
+// files (e.g. Dockerfile.dockerignore).
+func dockerfiles(fsys fs.FS) []string {
+	var dockerfiles []string
+	for _, name := range append(walk(fsys, "Dockerfile*"), walk(fsys, "Containerfile*")...) {
+		if !strings.HasSuffix(name, ".dockerignore") {
+			dockerfiles = append(dockerfiles, name)
+		}
+	}
+	return dockerfiles
+}
+
+// helper function returns the step name suffix for the
+// Dockerfile, which is the slug of the Dockerfile path
+// without the Dockerfile name. The root Dockerfile and
+// Containerfile return an empty string.
+func dockerName(dockerfile string) string {
+	dir, file := path.Split(dockerfile)
+	file = strings.TrimPrefix(file, "Dockerfile")
+	file = strings.TrimPrefix(file, "Containerfile")
+	return slug(dir + file)
+}
+
+// helper function returns the build context for the
+// Dockerfile. The Dockerfile directory is used, unless the
+// files copied into the image only exist relative to the
+// repository root.
+func dockerContext(fsys fs.FS, dockerfile string, parsed *dockerfileSpec) string {
+	dir := path.Dir(dockerfile)
+	if dir == "." {
+		return dir
+	}
+	for _, source := range parsed.sources {
+		if !exists(fsys, path.Join(dir, source)) && exists(fsys, source) {
+			return "."
+		}
+	}
+	return dir
+}
+
+// helper function returns the files copied into the image
+// that are excluded by the .dockerignore file. The
+// Dockerfile-specific ignore file takes precedence over the
+// ignore file in the build context.
+func dockerIgnored(fsys fs.FS, dockerfile, context string, parsed *dockerfileSpec) []string {
+	data, err := read(fsys, dockerfile+".dockerignore")
+	if err != nil {
+		data, err = read(fsys, path.Join(context, ".dockerignore"))
+	}
+	if err != nil {
+		return nil
+	}
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && line[0] != '#' {
+			patterns = append(patterns, line)
+		}
+	}
+	var ignored []string
+	for _, source := range parsed.sources {
+		if isDockerIgnored(patterns, source) {
+			ignored = append(ignored, source)
+		}
+	}
+	return ignored
+}
+
+// helper function returns true if the path is excluded by
+// the .dockerignore patterns. The last matching pattern
+// wins, and patterns prefixed with ! re-include the path.
+func isDockerIgnored(patterns []string, name string) bool {
+	var ignored bool
+	for _, pattern := range patterns {
+		exclude := !strings.HasPrefix(pattern, "!")
+		pattern = path.Clean(strings.TrimPrefix(strings.TrimPrefix(pattern, "!"), "/"))
+		if matchDockerIgnore(pattern, name) {
+			ignored = exclude
+		}
+	}
+	return ignored
+}
+
+// helper function returns true if the .dockerignore pattern
+// matches the path or one of its parent directories. A
+// leading **/ matches any number of directories.
+func matchDockerIgnore(pattern, name string) bool {
+	segments := strings.Split(name, "/")
+	for i := range segments {
+		for j := i + 1; j <= len(segments); j++ {
+			if i != 0 && !strings.HasPrefix(pattern, "**/") {
+				break
+			}
+			candidate := strings.Join(segments[i:j], "/")
+			if ok, _ := path.Match(strings.TrimPrefix(pattern, "**/"), candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// helper function returns the origin url from the
+// .git/config file.
+func gitRemote(fsys fs.FS) string {
+	data, _ := read(fsys, ".git/config")
+	if match := gitOrigin.FindSubmatch(data); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// helper function returns the image repository name from the
+// git remote url (e.g. https://github.com/octocat/hello-world.git
+// or git@github.com:octocat/hello-world.git).
+func dockerRepo(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+	if i := strings.Index(remote, "://"); i != -1 {
+		// strip the scheme, credentials and host.
+		remote = remote[i+3:]
+		if i := strings.Index(remote, "/"); i != -1 {
+			remote = remote[i+1:]
+		} else {
+			remote = ""
+		}
+	} else if i := strings.Index(remote, ":"); i != -1 {
+		// strip the user and host from the scp-like url.
+		remote = remote[i+1:]
+	} else {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(remote, "/"))
+}
+
+// represents the parsed Dockerfile.
+type dockerfileSpec struct {
+	stages  []string // named build stages
+	images  []string // base images, excluding build stages
+	args    []string // build arguments without a default value
+	sources []string // files copied from the build context
+}
+
+// helper function parses the Dockerfile instructions used to
+// configure the docker steps.
+func parseDockerfile(data []byte) *dockerfileSpec {
+	file := new(dockerfileSpec)
+	stages := map[string]bool{}
+	args := map[string]bool{}
+
+	// join the instructions split across multiple lines.
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\\\n", " ")
+
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		// ignore the instruction flags (e.g. --platform).
+		var params []string
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "--") {
+				params = append(params, field)
+			}
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "FROM":
+			if len(params) == 0 {
+				continue
+			}
+			if image := strings.ToLower(params[0]); !stages[image] {
+				file.images = append(file.images, image)
+			}
+			if len(params) == 3 && strings.EqualFold(params[1], "as") {
+				name := strings.ToLower(params[2])
+				stages[name] = true
+				file.stages = append(file.stages, name)
+			}
+		case "ARG":
+			for _, arg := range params {
+				if !strings.Contains(arg, "=") && !dockerBuiltinArgs[arg] && !args[arg] {
+					args[arg] = true
+					file.args = append(file.args, arg)
+				}
+			}
+		case "COPY", "ADD":
+			// files copied from another stage or image, and
+			// the json form, are ignored.
+			if strings.Contains(line, "--from=") || len(params) < 2 || strings.HasPrefix(params[0], "[") {
+				continue
+			}
+			for _, source := range params[:len(params)-1] {
+				source = path.Clean(source)
+				if source == "." || strings.ContainsAny(source, "*?[$") || strings.Contains(source, "://") {
+					continue
+				}
+				file.sources = append(file.sources, source)
+			}
+		}
+	}
+	return file
+}
+
+// targets returns the well-known build stages.
+func (d *dockerfileSpec) targets() []string {
+	var targets []string
+	for _, stage := range d.stages {
+		if dockerTargets[stage] {
+			targets = append(targets, stage)
+		}
+	}
+	return targets
+}
+
+// isMultiArch returns true if all base images are known to
+// publish arm64 variants.
+func (d *dockerfileSpec) isMultiArch() bool {
+	if len(d.images) == 0 {
+		return false
+	}
+	for _, image := range d.images {
+		// strip the digest, tag and default registry.
+		image, _, _ = strings.Cut(image, "@")
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			image = image[:i]
+		}
+		image = strings.TrimPrefix(image, "docker.io/")
+		image = strings.TrimPrefix(image, "library/")
+		if !dockerMultiArch[image] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// ConfigureDotnet configures a .NET step.
+func ConfigureDotnet(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	// check for the solution or project files.
+	solutions := glob(fsys, "*.sln")
+	projects := dotnetProjects(fsys)
+	if len(solutions) == 0 && len(projects) == 0 {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "mcr.microsoft.com/dotnet/sdk"
+		if v := dotnetVersion(fsys, projects); v != "" {
+			image = image + ":" + v
+		}
+	}
+
+	// build the solution, if exists, otherwise build
+	// each project individually.
+	targets := projects
+	if len(solutions) != 0 {
+		targets = solutions[:1]
+	}
+
+	// helper function returns the dotnet command for
+	// each build target.
+	command := func(command string) string {
+		var commands []string
+		for _, target := range targets {
+			commands = append(commands, "dotnet "+command+" "+quote(target))
+		}
+		return strings.Join(commands, " && ")
+	}
+
+	// packages are restored to the home directory by
+	// default, which is not shared between steps, so the
+	// packages are restored to the workspace.
+	steps := []*spec.Step{
+		createScriptStep(image,
+			"dotnet_restore",
+			command("restore"),
+		),
+		createScriptStep(image,
+			"dotnet_build",
+			command("build --no-restore"),
+		),
+		createScriptStep(image,
+			"dotnet_test",
+			command("test --no-build"),
+		),
+	}
+	for _, step := range steps {
+		step.Run.Env = map[string]string{
+			"NUGET_PACKAGES": ".nuget/packages",
+		}
+	}
+	stage.Steps = append(stage.Steps, steps...)
+
+	return nil
+}
+
+// helper function returns the c# and f# projects in the
+// root or well-known subdirectories of the repository.
+func dotnetProjects(fsys fs.FS) []string {
+	var projects []string
+	for _, pattern := range []string{
+		"*.csproj",
+		"*.fsproj",
+		"*/*.csproj",
+		"*/*.fsproj",
+		"*/*/*.csproj",
+		"*/*/*.fsproj",
+	} {
+		projects = append(projects, glob(fsys, pattern)...)
+	}
+	return projects
+}
+
+// helper function returns the .NET sdk version from the
+// global.json file or the highest target framework of the
+// projects.
+func dotnetVersion(fsys fs.FS, projects []string) string {
+	file := new(globalJson)
+	if err := unmarshal(fsys, "global.json", file); err == nil {
+		if v := minorVersion(file.Sdk.Version); v != "" {
+			return v
+		}
+	}
+
+	var version string
+	for _, name := range projects {
+		project := new(dotnetProject)
+		if err := unmarshalXml(fsys, name, project); err != nil {
+			continue
+		}
+		for _, group := range project.PropertyGroup {
+			frameworks := group.TargetFramework + ";" + group.TargetFrameworks
+			for _, framework := range strings.Split(frameworks, ";") {
+				if v := dotnetFramework(framework); compareVersion(v, version) > 0 {
+					version = v
+				}
+			}
+		}
+	}
+	return version
+}
+
+// helper function returns the .NET version from the target
+// framework moniker (e.g. net8.0). The .NET Framework and
+// .NET Standard monikers are ignored.
+func dotnetFramework(s string) string {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "netcoreapp"):
+		s = strings.TrimPrefix(s, "netcoreapp")
+	case strings.HasPrefix(s, "netstandard"):
+		return ""
+	case strings.HasPrefix(s, "net"):
+		s = strings.TrimPrefix(s, "net")
+	default:
+		return ""
+	}
+	if !strings.Contains(s, ".") {
+		return ""
+	}
+	return minorVersion(s)
+}
+
+// represents the global.json file format.
+type globalJson struct {
+	Sdk struct {
+		Version string `json:"version"`
+	} `json:"sdk"`
+}
+
+// represents the csproj and fsproj file format.
+type dotnetProject struct {
+	PropertyGroup []struct {
+		TargetFramework  string `xml:"TargetFramework"`
+		TargetFrameworks string `xml:"TargetFrameworks"`
+	} `xml:"PropertyGroup"`
+}
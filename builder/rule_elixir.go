@@ -0,0 +1,201 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"bytes"
+	"io/fs"
+	"regexp"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expressions to extract the elixir version
+// requirement from the mix.exs file, and the database
+// hostname from the config/test.exs file.
+var (
+	mixElixir   = regexp.MustCompile(`elixir:\s*"([^"]+)"`)
+	mixHostname = regexp.MustCompile(`hostname:\s*"([^"]+)"`)
+)
+
+// ConfigureElixir configures an Elixir or Erlang step for
+// mix and rebar3 projects.
+func ConfigureElixir(fsys fs.FS, pipeline *spec.Pipeline) error {
+	switch {
+	case exists(fsys, "mix.exs"):
+		configureMix(fsys, pipeline)
+	case exists(fsys, "rebar.config"):
+		configureRebar(fsys, pipeline)
+	}
+	return nil
+}
+
+// helper function configures the mix steps.
+func configureMix(fsys fs.FS, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	mixexs, _ := read(fsys, "mix.exs")
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "elixir"
+		if v := elixirVersion(fsys, mixexs); v != "" {
+			image = "elixir:" + v
+		}
+	}
+
+	// phoenix applications using ecto require a database
+	// to run the tests.
+	var env map[string]string
+	if bytes.Contains(mixexs, []byte("{:phoenix,")) &&
+		(bytes.Contains(mixexs, []byte("{:ecto_sql,")) ||
+			bytes.Contains(mixexs, []byte("{:phoenix_ecto,"))) {
+		database := postgresService
+
+		// the generated test configuration connects to
+		// the configured hostname and ignores the
+		// DATABASE_URL, so the url is only provided if
+		// the test configuration reads it. otherwise the
+		// service is named after the configured hostname.
+		// services are reachable on localhost when the
+		// steps share the network (e.g. kubernetes).
+		config, _ := read(fsys, "config/test.exs")
+		switch match := mixHostname.FindSubmatch(config); {
+		case bytes.Contains(config, []byte("DATABASE_URL")):
+			env = map[string]string{
+				"DATABASE_URL": database.url("ecto", "test"),
+			}
+		case match != nil && string(match[1]) != "localhost":
+			database.name = string(match[1])
+		}
+		stage.Steps = append(stage.Steps, database.step())
+	}
+
+	// hex and rebar are installed in the home directory,
+	// which is not shared between steps.
+	setup := "mix local.hex --force && mix local.rebar --force && "
+
+	steps := []*spec.Step{
+		createScriptStep(image,
+			"mix_deps",
+			setup+"mix deps.get",
+		),
+		createScriptStep(image,
+			"mix_compile",
+			setup+"mix compile --warnings-as-errors",
+		),
+		createScriptStep(image,
+			"mix_format",
+			"mix format --check-formatted",
+		),
+	}
+
+	// add the credo step if listed in the dependencies
+	if bytes.Contains(mixexs, []byte("{:credo,")) {
+		steps = append(steps, createScriptStep(image,
+			"mix_credo",
+			setup+"mix credo",
+		))
+	}
+
+	// add the dialyzer step if listed in the dependencies
+	if bytes.Contains(mixexs, []byte("{:dialyxir,")) {
+		steps = append(steps, createScriptStep(image,
+			"mix_dialyzer",
+			setup+"mix dialyzer",
+		))
+	}
+
+	steps = append(steps, createScriptStep(image,
+		"mix_test",
+		setup+"mix test",
+	))
+
+	for _, step := range steps {
+		step.Run.Env = env
+	}
+	stage.Steps = append(stage.Steps, steps...)
+}
+
+// helper function configures the rebar3 steps.
+func configureRebar(fsys fs.FS, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	config, _ := read(fsys, "rebar.config")
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "erlang"
+		if v := majorVersion(toolVersion(fsys, "erlang")); v != "" {
+			image = "erlang:" + v
+		}
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"rebar3_deps",
+		"rebar3 get-deps",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"rebar3_compile",
+		"rebar3 compile",
+	))
+
+	// add the format step if the erlfmt plugin is
+	// configured.
+	if bytes.Contains(config, []byte("erlfmt")) {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"rebar3_fmt",
+			"rebar3 fmt --check",
+		))
+	}
+
+	// add the dialyzer step if configured.
+	if bytes.Contains(config, []byte("dialyzer")) {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"rebar3_dialyzer",
+			"rebar3 dialyzer",
+		))
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"rebar3_eunit",
+		"rebar3 eunit",
+	))
+
+	// add the common test step if test suites exist.
+	if match(fsys, "test/*_SUITE.erl") {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"rebar3_ct",
+			"rebar3 ct",
+		))
+	}
+}
+
+// helper function returns the elixir version from the
+// .tool-versions file or the mix.exs elixir requirement.
+func elixirVersion(fsys fs.FS, mixexs []byte) string {
+	if v := minorVersion(toolVersion(fsys, "elixir")); v != "" {
+		return v
+	}
+	if match := mixElixir.FindSubmatch(mixexs); match != nil {
+		return minorVersion(string(match[1]))
+	}
+	return ""
+}
@@ -16,6 +16,8 @@ package builder
 
 import (
 	"io/fs"
+	"path"
+	"strings"
 
 	spec "github.com/bradrydzewski/spec/yaml"
 )
@@ -24,62 +26,148 @@ import (
 func ConfigureGo(fsys fs.FS, pipeline *spec.Pipeline) error {
 	stage := pipeline.Stages[0]
 
-	// check for the go.mod file.
-	if !exists(fsys, "go.mod") {
+	// check for the go.mod or go.work file.
+	if !exists(fsys, "go.mod") && !exists(fsys, "go.work") {
 		return nil
 	}
 
 	// check if we should use a container-based
 	// execution environment.
-	useImage := isContainerRuntime(pipeline)
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "golang:" + goVersion(fsys)
+	}
 
-	// add the go install step
-	if exists(fsys, "main.go") {
-		run := new(spec.StepRun)
-		run.Script = []string{"go build"}
+	// the package patterns for each module in the go.work
+	// file, or the root module.
+	packages := strings.Join(goPackages(fsys), " ")
 
-		if useImage {
-			run.Container = new(spec.Container)
-			run.Container.Image = "golang:1"
+	// add the go install step
+	commands := glob(fsys, "cmd/*/main.go")
+	switch {
+	case exists(fsys, "main.go"):
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"go_install",
+			"go build",
+		))
+	case len(commands) != 0:
+		for _, command := range commands {
+			name := path.Base(path.Dir(command))
+			stage.Steps = append(stage.Steps, createScriptStep(image,
+				"go_build_"+slug(name),
+				"go build -o bin/"+name+" ./cmd/"+name,
+			))
 		}
+	default:
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"go_install",
+			"go install "+packages,
+		))
+	}
 
-		step := new(spec.Step)
-		step.Name = "go_install"
-		step.Run = run
-
-		stage.Steps = append(stage.Steps, step)
-	} else {
-		run := new(spec.StepRun)
-		run.Script = []string{"go install ./..."}
+	// add the go vet step
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"go_vet",
+		"go vet "+packages,
+	))
 
-		if useImage {
-			run.Container = new(spec.Container)
-			run.Container.Image = "golang:1"
+	// add the go test step
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"go_test",
+		"go test -v "+packages,
+	))
+
+	// add the golangci-lint step if configured
+	if match(fsys, ".golangci.*") {
+		var image string
+		if isContainerRuntime(pipeline) {
+			image = "golangci/golangci-lint"
 		}
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"golangci_lint",
+			"golangci-lint run "+packages,
+		))
+	}
 
-		step := new(spec.Step)
-		step.Name = "go_install"
-		step.Run = run
-
-		stage.Steps = append(stage.Steps, step)
+	// add the goreleaser step if configured
+	if match(fsys, ".goreleaser.*") || match(fsys, "goreleaser.*") {
+		var image string
+		if isContainerRuntime(pipeline) {
+			image = "goreleaser/goreleaser"
+		}
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"goreleaser_snapshot",
+			"goreleaser check && goreleaser release --snapshot --clean",
+		))
 	}
 
-	// add the go test step
-	{
-		run := new(spec.StepRun)
-		run.Script = []string{"go test -v ./..."}
+	return nil
+}
 
-		if useImage {
-			run.Container = new(spec.Container)
-			run.Container.Image = "golang:1"
+// helper function returns the package patterns for each
+// module used by the go.work file, or the root module if
+// the project does not use a workspace.
+func goPackages(fsys fs.FS) []string {
+	var packages []string
+	for _, dir := range goDirectives(fsys, "go.work", "use") {
+		dir = strings.Trim(path.Clean(dir), "/")
+		if dir == "." {
+			packages = append(packages, "./...")
+		} else {
+			packages = append(packages, "./"+dir+"/...")
 		}
+	}
+	if len(packages) == 0 {
+		return []string{"./..."}
+	}
+	return packages
+}
 
-		step := new(spec.Step)
-		step.Name = "go_test"
-		step.Run = run
+// helper function returns the golang image tag from the
+// toolchain or go directive in the go.work or go.mod file,
+// defaulting to the latest version.
+func goVersion(fsys fs.FS) string {
+	for _, name := range []string{"go.work", "go.mod"} {
+		if v := goDirectives(fsys, name, "toolchain"); len(v) != 0 {
+			if v := strings.TrimPrefix(v[0], "go"); version.MatchString(v) {
+				return v
+			}
+		}
+		if v := goDirectives(fsys, name, "go"); len(v) != 0 {
+			if v := minorVersion(v[0]); v != "" {
+				return v
+			}
+		}
+	}
+	return "1"
+}
 
-		stage.Steps = append(stage.Steps, step)
+// helper function returns the arguments of the named
+// directive in the go.mod or go.work file, including the
+// arguments in a directive block.
+func goDirectives(fsys fs.FS, name, directive string) []string {
+	data, err := read(fsys, name)
+	if err != nil {
+		return nil
 	}
 
-	return nil
+	var args []string
+	var block bool
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case block && len(fields) != 0 && fields[0] == ")":
+			block = false
+		case block && len(fields) != 0:
+			args = append(args, fields[0])
+		case len(fields) >= 2 && fields[0] == directive && fields[1] == "(":
+			block = true
+		case len(fields) >= 2 && fields[0] == directive:
+			args = append(args, fields[1])
+		}
+	}
+	return args
 }
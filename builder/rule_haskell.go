@@ -0,0 +1,116 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"regexp"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expressions to extract the ghc version from the
+// stack.yaml, cabal.project and cabal files.
+var (
+	stackResolver = regexp.MustCompile(`(?m)^(?:resolver|snapshot|compiler):\s*['"]?(lts-\d+|ghc-[\d.]+)`)
+	cabalCompiler = regexp.MustCompile(`(?m)^with-compiler:\s*ghc-([\d.]+)`)
+	cabalTested   = regexp.MustCompile(`(?mi)^tested-with:\s*ghc\s*==\s*([\d.]+)`)
+)
+
+// stackage lts snapshots mapped to the ghc version.
+var stackageGhc = map[string]string{
+	"lts-18": "8.10",
+	"lts-19": "9.0",
+	"lts-20": "9.2",
+	"lts-21": "9.4",
+	"lts-22": "9.6",
+	"lts-23": "9.8",
+	"lts-24": "9.10",
+}
+
+// ConfigureHaskell configures a Haskell step for Stack and
+// Cabal projects.
+func ConfigureHaskell(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	cabal := glob(fsys, "*.cabal")
+	isStack := exists(fsys, "stack.yaml")
+	if !isStack && !exists(fsys, "cabal.project") && len(cabal) == 0 {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment. the haskell image
+	// includes both stack and cabal.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "haskell"
+		if v := ghcVersion(fsys, cabal); v != "" {
+			image = "haskell:" + v
+		}
+	}
+
+	if isStack {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"stack_build",
+			"stack build --test --no-run-tests",
+		))
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"stack_test",
+			"stack test",
+		))
+		return nil
+	}
+
+	// the package index is stored in the home directory,
+	// which is not shared between steps.
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"cabal_build",
+		"cabal update && cabal build all --enable-tests",
+	))
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"cabal_test",
+		"cabal update && cabal test all",
+	))
+	return nil
+}
+
+// helper function returns the ghc major and minor version
+// from the stack resolver, the cabal.project compiler or the
+// tested-with field in the cabal file.
+func ghcVersion(fsys fs.FS, cabal []string) string {
+	stack, _ := read(fsys, "stack.yaml")
+	if match := stackResolver.FindSubmatch(stack); match != nil {
+		resolver := string(match[1])
+		if strings.HasPrefix(resolver, "ghc-") {
+			return minorVersion(resolver)
+		}
+		// unknown snapshots return an empty version,
+		// which uses the latest image.
+		return stackageGhc[resolver]
+	}
+	project, _ := read(fsys, "cabal.project")
+	if match := cabalCompiler.FindSubmatch(project); match != nil {
+		return minorVersion(string(match[1]))
+	}
+	for _, name := range cabal {
+		data, _ := read(fsys, name)
+		if match := cabalTested.FindSubmatch(data); match != nil {
+			return minorVersion(string(match[1]))
+		}
+	}
+	return ""
+}
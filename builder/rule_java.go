@@ -0,0 +1,289 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/fs"
+	"regexp"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expressions to extract the java version from the
+// gradle build file.
+var gradleJava = []*regexp.Regexp{
+	regexp.MustCompile(`JavaLanguageVersion\.of\(\s*['"]?(\d+)`),
+	regexp.MustCompile(`jvmToolchain\(\s*(\d+)`),
+	regexp.MustCompile(`(?:source|target)Compatibility\s*=\s*(?:JavaVersion\.VERSION_)?['"]?(\d+(?:[._]\d+)?)`),
+}
+
+// regular expressions to extract the java version from the
+// .sdkmanrc file and the java home in the .jvmopts file.
+var (
+	sdkmanJava  = regexp.MustCompile(`(?m)^\s*java\s*=\s*([\d.]+)`)
+	jvmoptsJava = regexp.MustCompile(`(?:jdk|java)-?(\d+(?:\.\d+)?)`)
+)
+
+// ConfigureJava configures a Java or Kotlin step for
+// Maven and Gradle projects.
+func ConfigureJava(fsys fs.FS, pipeline *spec.Pipeline) error {
+	switch {
+	case exists(fsys, "pom.xml"):
+		configureMaven(fsys, pipeline)
+	case exists(fsys, "build.gradle"),
+		exists(fsys, "build.gradle.kts"),
+		exists(fsys, "settings.gradle"),
+		exists(fsys, "settings.gradle.kts"):
+		configureGradle(fsys, pipeline)
+	}
+	return nil
+}
+
+// helper function configures the maven steps.
+func configureMaven(fsys fs.FS, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	// parse the pom.xml file
+	pom := new(pomXml)
+	unmarshalXml(fsys, "pom.xml", pom)
+
+	// prefer the maven wrapper, if exists, since it
+	// pins the maven version used by the project.
+	command := "mvn -B"
+	if exists(fsys, "mvnw") {
+		command = "./mvnw -B"
+	}
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = mavenImage(fsys, pom)
+	}
+
+	// multi-module projects are built by the reactor,
+	// which should test every module before failing.
+	var flags string
+	if len(pom.Modules) != 0 {
+		flags = " --fail-at-end"
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"maven_compile",
+		command+" compile",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"maven_test",
+		command+" test"+flags,
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"maven_package",
+		command+" package -DskipTests",
+	))
+}
+
+// helper function configures the gradle steps.
+func configureGradle(fsys fs.FS, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	// prefer the gradle wrapper, if exists, since it
+	// pins the gradle version used by the project.
+	command := "gradle"
+	if exists(fsys, "gradlew") {
+		command = "./gradlew"
+	}
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = gradleImage(fsys)
+	}
+
+	// multi-project builds execute the task in every
+	// project, which should test every project before
+	// failing.
+	var flags string
+	for _, name := range []string{"settings.gradle", "settings.gradle.kts"} {
+		data, _ := read(fsys, name)
+		if bytes.Contains(data, []byte("include")) {
+			flags = " --continue"
+		}
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"gradle_compile",
+		command+" classes testClasses",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"gradle_test",
+		command+" test"+flags,
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"gradle_package",
+		command+" assemble",
+	))
+}
+
+// helper function returns the java version from the gradle
+// toolchain or compatibility settings.
+func gradleJavaVersion(fsys fs.FS) string {
+	for _, name := range []string{"build.gradle", "build.gradle.kts"} {
+		data, _ := read(fsys, name)
+		for _, re := range gradleJava {
+			if match := re.FindSubmatch(data); match != nil {
+				return javaVersion(string(match[1]))
+			}
+		}
+	}
+	return ""
+}
+
+// helper function returns the java version from the
+// .java-version, .sdkmanrc or .tool-versions file, or the
+// java home configured in the .jvmopts file.
+func jdkVersion(fsys fs.FS) string {
+	if v := javaVersion(readVersion(fsys, ".java-version")); v != "" {
+		return v
+	}
+
+	// the sdkman version includes the vendor suffix
+	// (e.g. java=17.0.9-tem).
+	sdkmanrc, _ := read(fsys, ".sdkmanrc")
+	if match := sdkmanJava.FindSubmatch(sdkmanrc); match != nil {
+		return javaVersion(string(match[1]))
+	}
+
+	// the asdf version includes the vendor prefix
+	// (e.g. temurin-17.0.9+9).
+	for _, v := range strings.Split(toolVersion(fsys, "java"), "-") {
+		if v != "" && v[0] >= '0' && v[0] <= '9' {
+			return javaVersion(v)
+		}
+	}
+
+	jvmopts, _ := read(fsys, ".jvmopts")
+	if match := jvmoptsJava.FindSubmatch(jvmopts); match != nil {
+		return javaVersion(string(match[1]))
+	}
+	return ""
+}
+
+// helper function returns the major java version from the
+// version string, normalizing legacy versions (e.g. 1.8).
+func javaVersion(s string) string {
+	s = strings.ReplaceAll(s, "_", ".")
+	s = strings.TrimPrefix(s, "1.")
+	return majorVersion(s)
+}
+
+// helper function returns the maven image for the java
+// version, or the jdk image if the project includes the
+// maven wrapper.
+func mavenImage(fsys fs.FS, pom *pomXml) string {
+	version := pom.javaVersion()
+	if version == "" {
+		version = jdkVersion(fsys)
+	}
+	switch {
+	case exists(fsys, "mvnw"):
+		return jdkImage(version)
+	case version != "":
+		return "maven:3-eclipse-temurin-" + version
+	default:
+		return "maven:3"
+	}
+}
+
+// helper function returns the gradle image for the java
+// version, or the jdk image if the project includes the
+// gradle wrapper.
+func gradleImage(fsys fs.FS) string {
+	version := gradleJavaVersion(fsys)
+	if version == "" {
+		version = jdkVersion(fsys)
+	}
+	switch {
+	case exists(fsys, "gradlew"):
+		return jdkImage(version)
+	case version != "":
+		return "gradle:jdk" + version
+	default:
+		return "gradle"
+	}
+}
+
+// helper function returns the jdk image for the java
+// version, defaulting to the latest version.
+func jdkImage(version string) string {
+	if version == "" {
+		return "eclipse-temurin"
+	}
+	return "eclipse-temurin:" + version
+}
+
+// represents the pom.xml file format.
+type pomXml struct {
+	Modules    []string `xml:"modules>module"`
+	Properties struct {
+		Entries []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"properties"`
+}
+
+// property returns the named property, resolving property
+// references (e.g. ${java.version}).
+func (p *pomXml) property(name string) string {
+	value := p.lookup(name)
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+		return p.lookup(value[2 : len(value)-1])
+	}
+	return value
+}
+
+// lookup returns the raw value of the named property.
+func (p *pomXml) lookup(name string) string {
+	for _, entry := range p.Properties.Entries {
+		if entry.XMLName.Local == name {
+			return strings.TrimSpace(entry.Value)
+		}
+	}
+	return ""
+}
+
+// javaVersion returns the java version from the compiler
+// properties.
+func (p *pomXml) javaVersion() string {
+	for _, name := range []string{
+		"maven.compiler.release",
+		"maven.compiler.target",
+		"maven.compiler.source",
+		"java.version",
+	} {
+		if v := javaVersion(p.property(name)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expressions to detect the top-level apiVersion
+// and kind fields of a kubernetes manifest.
+var (
+	kubernetesApiVersion = regexp.MustCompile(`(?m)^apiVersion:\s*\S`)
+	kubernetesKind       = regexp.MustCompile(`(?m)^kind:\s*\S`)
+)
+
+// kubeconform command used to validate the manifests. Custom
+// resources without a published schema are skipped.
+const kubeconform = "kubeconform -strict -summary -ignore-missing-schemas"
+
+// ConfigureKubernetes configures steps to lint and validate
+// Helm charts, Kustomize overlays and Kubernetes manifests.
+func ConfigureKubernetes(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	charts := kubernetesDirs(fsys, "Chart.yaml")
+	overlays := kubernetesDirs(fsys, "kustomization.yaml", "kustomization.yml", "Kustomization")
+	manifests := kubernetesManifests(fsys, append(charts, overlays...))
+	if len(charts) == 0 && len(overlays) == 0 && len(manifests) == 0 {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment. kubeconform is not included
+	// in the helm or alpine images, so the release binary
+	// is installed in each step.
+	var helmImage, kustomizeImage, kubeconformImage, setup string
+	if isContainerRuntime(pipeline) {
+		helmImage = "alpine/helm"
+		kustomizeImage = "alpine"
+		kubeconformImage = "ghcr.io/yannh/kubeconform:latest-alpine"
+		setup = "wget -qO- https://github.com/yannh/kubeconform/releases/latest/download/kubeconform-linux-amd64.tar.gz" +
+			" | tar xz -C /usr/local/bin && "
+	}
+
+	for _, dir := range charts {
+		name := "helm"
+		if dir != "." {
+			name = "helm_" + slug(dir)
+		}
+
+		// fetch the chart dependencies, if any, which are
+		// required to lint and render the chart.
+		var deps string
+		if data, _ := read(fsys, path.Join(dir, "Chart.yaml")); bytes.Contains(data, []byte("dependencies:")) {
+			deps = "helm dependency build " + quote(dir) + " && "
+		}
+
+		stage.Steps = append(stage.Steps, createScriptStep(helmImage,
+			name+"_lint",
+			deps+"helm lint "+quote(dir),
+		))
+		stage.Steps = append(stage.Steps, createScriptStep(helmImage,
+			name+"_template",
+			setup+deps+"helm template "+quote(dir)+" | "+kubeconform,
+		))
+	}
+
+	for _, dir := range overlays {
+		name := "kustomize_build"
+		if dir != "." {
+			name = "kustomize_build_" + slug(dir)
+		}
+		var install string
+		if kustomizeImage != "" {
+			install = "apk add --no-cache kustomize && "
+		}
+		stage.Steps = append(stage.Steps, createScriptStep(kustomizeImage,
+			name,
+			install+setup+"kustomize build "+quote(dir)+" | "+kubeconform,
+		))
+	}
+
+	if len(manifests) != 0 {
+		for i, name := range manifests {
+			manifests[i] = quote(name)
+		}
+		stage.Steps = append(stage.Steps, createScriptStep(kubeconformImage,
+			"kubeconform",
+			kubeconform+" "+strings.Join(manifests, " "),
+		))
+	}
+
+	return nil
+}
+
+// helper function returns the directories that contain one
+// of the named files, excluding directories nested inside
+// another matching directory (e.g. helm subcharts).
+func kubernetesDirs(fsys fs.FS, names ...string) []string {
+	var dirs []string
+	for _, name := range names {
+		for _, file := range walk(fsys, name) {
+			dir := path.Dir(file)
+			if !isNested(dir, dirs) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// helper function returns the yaml files that define a
+// kubernetes resource, excluding files in the chart and
+// kustomize directories, which are validated separately.
+func kubernetesManifests(fsys fs.FS, exclude []string) []string {
+	var manifests []string
+	for _, name := range append(walk(fsys, "*.yaml"), walk(fsys, "*.yml")...) {
+		if isNested(path.Dir(name), exclude) {
+			continue
+		}
+		data, _ := read(fsys, name)
+		if kubernetesApiVersion.Match(data) && kubernetesKind.Match(data) {
+			manifests = append(manifests, name)
+		}
+	}
+	return manifests
+}
+
+// helper function returns true if the directory is equal to
+// or nested inside one of the parent directories.
+func isNested(dir string, parents []string) bool {
+	for _, parent := range parents {
+		if parent == "." || dir == parent || strings.HasPrefix(dir, parent+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,8 @@ package builder
 
 import (
 	"io/fs"
+	"path"
+	"strings"
 
 	spec "github.com/bradrydzewski/spec/yaml"
 )
@@ -29,71 +31,421 @@ func ConfigureNode(fsys fs.FS, pipeline *spec.Pipeline) error {
 		return nil
 	}
 
+	// parse the package.json file and unmarshal
+	json := new(packageJson)
+	err := unmarshal(fsys, "package.json", &json)
+
+	// detect the package manager used to install
+	// dependencies and run scripts.
+	manager := nodePackageManager(fsys, json)
+
+	// pnpm and yarn berry are enabled using corepack, which
+	// is repeated in every step since steps do not share
+	// the container.
+	setup := nodeSetup(fsys, manager, json)
+
 	// check if we should use a container-based
 	// execution environment.
 	var image string
 	if isContainerRuntime(pipeline) {
 		image = "node"
+		if v := nodeVersion(fsys, json); v != "" {
+			image = image + ":" + v
+		}
+		if manager == "bun" {
+			image = "oven/bun"
+		}
 	}
 
-	// add the npm install step
+	// add the install step
 	stage.Steps = append(stage.Steps, createScriptStep(image,
-		"npm_install",
-		"npm install",
+		manager+"_install",
+		nodeInstall(fsys, manager, json),
 	))
 
-	// parse the package.json file and unmarshal
-	json := new(packageJson)
-	if err := unmarshal(fsys, "package.json", &json); err != nil {
+	// ignore scripts if the package.json is invalid
+	if err != nil {
 		return nil
 	}
 
+	// monorepos orchestrated by turborepo or nx are
+	// built with a single step that runs the well-known
+	// tasks for every package.
+	if name, command := nodeOrchestrator(fsys, manager); command != "" {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			name,
+			setup+command,
+		))
+		return nil
+	}
+
+	// monorepos using workspaces are built with steps for
+	// each package, using the package scripts.
+	if packages := nodeWorkspaces(fsys, json); len(packages) != 0 {
+		for _, dir := range packages {
+			pkg := new(packageJson)
+			if err := unmarshal(fsys, path.Join(dir, "package.json"), pkg); err != nil {
+				continue
+			}
+			name := pkg.Name
+			if name == "" {
+				name = dir
+			}
+			configureNodeScripts(stage, image, manager+"_"+slug(name), pkg,
+				func(script string) string {
+					return setup + nodeWorkspaceRun(manager, name, dir, script)
+				},
+			)
+		}
+		return nil
+	}
+
+	configureNodeScripts(stage, image, manager, json,
+		func(script string) string {
+			return setup + manager + " run " + script
+		},
+	)
+
+	return nil
+}
+
+// helper function appends steps for the well-known scripts
+// in the package.json file. The run function returns the
+// command used to run the named script.
+func configureNodeScripts(stage *spec.Stage, image, prefix string, json *packageJson, run func(string) string) {
 	// add well-known test
 	if _, ok := json.Scripts["test"]; ok {
 		stage.Steps = append(stage.Steps, createScriptStep(image,
-			"npm_test",
-			"npm run test",
+			prefix+"_test",
+			run("test"),
 		))
 	}
 
 	// add well-known lint command
 	if _, ok := json.Scripts["lint"]; ok {
 		stage.Steps = append(stage.Steps, createScriptStep(image,
-			"npm_test",
-			"npm run lint",
+			prefix+"_lint",
+			run("lint"),
 		))
 	}
 
 	// add well-known e2e command
 	if _, ok := json.Scripts["e2e"]; ok {
 		stage.Steps = append(stage.Steps, createScriptStep(image,
-			"npm_e2e",
-			"npm run e2e",
+			prefix+"_e2e",
+			run("e2e"),
 		))
 	}
 
 	// add well-known e2e docker if infra is cloud
 	if _, ok := json.Scripts["e2e:docker"]; ok && image == "" {
 		stage.Steps = append(stage.Steps, createScriptStep(image,
-			"npm_e2e_docker",
-			"npm run e2e docker",
+			prefix+"_e2e_docker",
+			run("e2e docker"),
 		))
 	}
 
 	// add well-known dist command
 	if _, ok := json.Scripts["dist"]; ok {
 		stage.Steps = append(stage.Steps, createScriptStep(image,
-			"npm_dist",
-			"npm run dist",
+			prefix+"_dist",
+			run("dist"),
 		))
 	}
+}
 
-	return nil
+// well-known monorepo tasks, in execution order.
+var nodeTasks = []string{"lint", "test", "build"}
+
+// helper function returns the step name and command used to
+// run the well-known tasks with the turborepo or nx monorepo
+// orchestrator, if configured.
+func nodeOrchestrator(fsys fs.FS, manager string) (string, string) {
+	if exists(fsys, "turbo.json") {
+		file := new(turboJson)
+		unmarshal(fsys, "turbo.json", file)
+		tasks := nodeTaskNames(file.Tasks, file.Pipeline)
+		return "turbo_run", nodeExec(manager) + " turbo run " + strings.Join(tasks, " ")
+	}
+	if exists(fsys, "nx.json") {
+		file := new(nxJson)
+		unmarshal(fsys, "nx.json", file)
+		tasks := nodeTaskNames(file.TargetDefaults)
+		return "nx_affected", nodeExec(manager) + " nx affected -t " + strings.Join(tasks, " ")
+	}
+	return "", ""
+}
+
+// helper function returns the well-known tasks defined in the
+// task configurations, defaulting to all well-known tasks.
+// Package-specific tasks (e.g. web#build) are matched by the
+// task name.
+func nodeTaskNames(configs ...map[string]interface{}) []string {
+	var tasks []string
+	for _, task := range nodeTasks {
+		if hasNodeTask(task, configs) {
+			tasks = append(tasks, task)
+		}
+	}
+	if len(tasks) == 0 {
+		return nodeTasks
+	}
+	return tasks
+}
+
+// helper function returns true if the task is defined in
+// any of the task configurations.
+func hasNodeTask(task string, configs []map[string]interface{}) bool {
+	for _, config := range configs {
+		for name := range config {
+			if name == task || strings.HasSuffix(name, "#"+task) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// helper function returns the package directories of the
+// monorepo workspaces, defined by the package.json file,
+// the pnpm-workspace.yaml file or the lerna.json file.
+func nodeWorkspaces(fsys fs.FS, json *packageJson) []string {
+	patterns := json.workspaces()
+	if len(patterns) == 0 {
+		file := new(pnpmWorkspace)
+		if err := unmarshalYaml(fsys, "pnpm-workspace.yaml", file); err == nil {
+			patterns = file.Packages
+		}
+	}
+	if len(patterns) == 0 {
+		file := new(lernaJson)
+		if err := unmarshal(fsys, "lerna.json", file); err == nil {
+			patterns = file.Packages
+			if len(patterns) == 0 {
+				patterns = []string{"packages/*"}
+			}
+		}
+	}
+
+	// expand the glob patterns, excluding negated patterns
+	// and directories without a package.json file.
+	var dirs []string
+	excluded := map[string]bool{}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+		pattern = strings.ReplaceAll(pattern, "**", "*")
+		if strings.HasPrefix(pattern, "!") {
+			for _, dir := range glob(fsys, pattern[1:]) {
+				excluded[dir] = true
+			}
+			continue
+		}
+		for _, dir := range glob(fsys, pattern) {
+			if exists(fsys, path.Join(dir, "package.json")) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	var packages []string
+	for _, dir := range dirs {
+		if !excluded[dir] {
+			packages = append(packages, dir)
+		}
+	}
+	return packages
+}
+
+// helper function returns the command used to run the named
+// script in the workspace package.
+func nodeWorkspaceRun(manager, name, dir, script string) string {
+	switch manager {
+	case "pnpm":
+		return "pnpm --filter " + quote(name) + " run " + script
+	case "yarn":
+		return "yarn workspace " + quote(name) + " run " + script
+	case "bun":
+		return "cd " + quote(dir) + " && bun run " + script
+	default:
+		return "npm run " + script + " --workspace=" + quote(dir)
+	}
+}
+
+// helper function returns the command used to execute a
+// package binary with the package manager.
+func nodeExec(manager string) string {
+	switch manager {
+	case "pnpm":
+		return "pnpm exec"
+	case "yarn":
+		return "yarn"
+	case "bun":
+		return "bunx"
+	default:
+		return "npx"
+	}
+}
+
+// helper function returns the package manager used by the
+// project, based on the packageManager field and lockfiles,
+// defaulting to npm.
+func nodePackageManager(fsys fs.FS, json *packageJson) string {
+	if name, _, ok := strings.Cut(json.PackageManager, "@"); ok {
+		switch name {
+		case "npm", "yarn", "pnpm", "bun":
+			return name
+		}
+	}
+	switch {
+	case exists(fsys, "bun.lockb"), exists(fsys, "bun.lock"):
+		return "bun"
+	case exists(fsys, "pnpm-lock.yaml"):
+		return "pnpm"
+	case exists(fsys, "yarn.lock"):
+		return "yarn"
+	default:
+		return "npm"
+	}
+}
+
+// helper function returns the command used to install the
+// project dependencies. The frozen lockfile variant is used
+// when a lockfile exists, and yarn and pnpm are enabled
+// using corepack.
+func nodeInstall(fsys fs.FS, manager string, json *packageJson) string {
+	switch manager {
+	case "bun":
+		if exists(fsys, "bun.lockb") || exists(fsys, "bun.lock") {
+			return "bun install --frozen-lockfile"
+		}
+		return "bun install"
+	case "pnpm":
+		if exists(fsys, "pnpm-lock.yaml") {
+			return "corepack enable && pnpm install --frozen-lockfile"
+		}
+		return "corepack enable && pnpm install"
+	case "yarn":
+		switch {
+		case !exists(fsys, "yarn.lock"):
+			return "corepack enable && yarn install"
+		case isYarnBerry(fsys, json):
+			return "corepack enable && yarn install --immutable"
+		default:
+			return "corepack enable && yarn install --frozen-lockfile"
+		}
+	default:
+		if exists(fsys, "package-lock.json") || exists(fsys, "npm-shrinkwrap.json") {
+			return "npm ci"
+		}
+		return "npm install"
+	}
+}
+
+// helper function returns the command used to enable the
+// pnpm and yarn berry package managers, which are not
+// included in the node image.
+func nodeSetup(fsys fs.FS, manager string, json *packageJson) string {
+	if manager == "pnpm" || (manager == "yarn" && isYarnBerry(fsys, json)) {
+		return "corepack enable && "
+	}
+	return ""
+}
+
+// helper function returns true if the project uses yarn
+// version 2 or higher, which replaces the frozen lockfile
+// flag with the immutable flag.
+func isYarnBerry(fsys fs.FS, json *packageJson) bool {
+	if _, v, ok := strings.Cut(json.PackageManager, "yarn@"); ok {
+		return majorVersion(v) != "1"
+	}
+	return exists(fsys, ".yarnrc.yml")
+}
+
+// lts codenames mapped to the node major version.
+var nodeCodenames = map[string]string{
+	"argon":    "4",
+	"boron":    "6",
+	"carbon":   "8",
+	"dubnium":  "10",
+	"erbium":   "12",
+	"fermium":  "14",
+	"gallium":  "16",
+	"hydrogen": "18",
+	"iron":     "20",
+	"jod":      "22",
+}
+
+// helper function returns the node image tag from the .nvmrc,
+// .node-version or .tool-versions file, or the engines field
+// in the package.json file.
+func nodeVersion(fsys fs.FS, json *packageJson) string {
+	for _, v := range []string{
+		readVersion(fsys, ".nvmrc"),
+		readVersion(fsys, ".node-version"),
+		toolVersion(fsys, "nodejs", "node"),
+		json.Engines.Node,
+	} {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if codename := strings.TrimPrefix(v, "lts/"); codename != v {
+			if major, ok := nodeCodenames[codename]; ok {
+				return major
+			}
+			return "lts"
+		}
+		if major := majorVersion(v); major != "" {
+			return major
+		}
+	}
+	return ""
 }
 
 // represents the package.json file format.
 type packageJson struct {
-	Name    string                 `json:"name"`
-	Version string                 `json:"version"`
-	Scripts map[string]interface{} `json:"scripts"`
+	Name           string                 `json:"name"`
+	Version        string                 `json:"version"`
+	PackageManager string                 `json:"packageManager"`
+	Scripts        map[string]interface{} `json:"scripts"`
+	Workspaces     interface{}            `json:"workspaces"`
+	Engines        struct {
+		Node string `json:"node"`
+	} `json:"engines"`
+}
+
+// workspaces returns the workspace package patterns, which
+// are defined as a list, or as an object with a packages
+// list (e.g. yarn classic).
+func (p *packageJson) workspaces() []string {
+	var patterns []string
+	v := p.Workspaces
+	if object, ok := v.(map[string]interface{}); ok {
+		v = object["packages"]
+	}
+	items, _ := v.([]interface{})
+	for _, item := range items {
+		if pattern, ok := item.(string); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// represents the turbo.json file format.
+type turboJson struct {
+	Pipeline map[string]interface{} `json:"pipeline"`
+	Tasks    map[string]interface{} `json:"tasks"`
+}
+
+// represents the nx.json file format.
+type nxJson struct {
+	TargetDefaults map[string]interface{} `json:"targetDefaults"`
+}
+
+// represents the lerna.json file format.
+type lernaJson struct {
+	Packages []string `json:"packages"`
+}
+
+// represents the pnpm-workspace.yaml file format.
+type pnpmWorkspace struct {
+	Packages []string `json:"packages"`
 }
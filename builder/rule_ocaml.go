@@ -0,0 +1,86 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"regexp"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expressions to extract the ocaml version
+// constraint from the opam and dune-project files.
+var (
+	opamOcaml = regexp.MustCompile(`"ocaml"\s*\{\s*>=?\s*"([\d.]+)"`)
+	duneOcaml = regexp.MustCompile(`\(ocaml\s*\(>=?\s*([\d.]+)\)`)
+)
+
+// ConfigureOCaml configures an OCaml step for dune and
+// opam projects.
+func ConfigureOCaml(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	opam := glob(fsys, "*.opam")
+	if !exists(fsys, "dune-project") && len(opam) == 0 {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "ocaml/opam"
+		if v := ocamlVersion(fsys, opam); v != "" {
+			image = "ocaml/opam:debian-ocaml-" + v
+		}
+	}
+
+	// dependencies are installed in the opam switch in the
+	// home directory, which is not shared between steps.
+	// projects without opam files only require dune.
+	setup := "opam install . --deps-only --with-test -y && "
+	if len(opam) == 0 {
+		setup = "opam install dune -y && "
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"dune_build",
+		setup+"opam exec -- dune build",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"dune_test",
+		setup+"opam exec -- dune test",
+	))
+
+	return nil
+}
+
+// helper function returns the minimum ocaml version from
+// the opam files or the dune-project file.
+func ocamlVersion(fsys fs.FS, opam []string) string {
+	for _, name := range opam {
+		data, _ := read(fsys, name)
+		if match := opamOcaml.FindSubmatch(data); match != nil {
+			return minorVersion(string(match[1]))
+		}
+	}
+	data, _ := read(fsys, "dune-project")
+	if match := duneOcaml.FindSubmatch(data); match != nil {
+		return minorVersion(string(match[1]))
+	}
+	return ""
+}
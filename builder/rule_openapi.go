@@ -0,0 +1,85 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expression to detect the top-level openapi or
+// swagger version field in a yaml or json specification.
+var openapiVersion = regexp.MustCompile(`(?m)^(?:\s*\{\s*)?"?(?:openapi|swagger)"?\s*:`)
+
+// ConfigureOpenAPI configures a spectral step to lint the
+// OpenAPI and Swagger specifications.
+func ConfigureOpenAPI(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	specs := openapiSpecs(fsys)
+	if len(specs) == 0 {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "stoplight/spectral"
+	}
+
+	for i, name := range specs {
+		specs[i] = quote(name)
+	}
+	command := "spectral lint " + strings.Join(specs, " ")
+
+	// spectral requires a ruleset. use the recommended
+	// openapi ruleset if the project does not include a
+	// ruleset.
+	if !match(fsys, ".spectral.*") {
+		command = `echo 'extends: ["spectral:oas"]' > .spectral.yaml && ` + command
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"spectral_lint",
+		command,
+	))
+
+	return nil
+}
+
+// helper function returns the openapi and swagger
+// specification files in the repository.
+func openapiSpecs(fsys fs.FS) []string {
+	var specs []string
+	for _, pattern := range []string{"openapi*", "swagger*", "*.openapi.*"} {
+		for _, name := range walk(fsys, pattern) {
+			switch path.Ext(name) {
+			case ".yaml", ".yml", ".json":
+			default:
+				continue
+			}
+			data, _ := read(fsys, name)
+			if openapiVersion.Match(data) {
+				specs = append(specs, name)
+			}
+		}
+	}
+	return specs
+}
@@ -0,0 +1,199 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"bytes"
+	"io/fs"
+	"sort"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// phpTool defines a well-known php tool that is installed
+// with composer.
+type phpTool struct {
+	name    string // tool name, used as the step name
+	binary  string // tool binary in the vendor/bin directory
+	pkg     string // composer package
+	command string // default command
+}
+
+// well-known php tools, in execution order.
+var phpTools = []phpTool{
+	{"php_cs_fixer", "php-cs-fixer", "friendsofphp/php-cs-fixer", "vendor/bin/php-cs-fixer fix --dry-run --diff"},
+	{"phpstan", "phpstan", "phpstan/phpstan", "vendor/bin/phpstan analyse"},
+	{"phpunit", "phpunit", "phpunit/phpunit", "vendor/bin/phpunit"},
+	{"pest", "pest", "pestphp/pest", "vendor/bin/pest"},
+}
+
+// ConfigurePHP configures a PHP step.
+func ConfigurePHP(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	// check for the composer.json file.
+	if !exists(fsys, "composer.json") {
+		return nil
+	}
+
+	// parse the composer.json file
+	composer := new(composerJson)
+	if err := unmarshal(fsys, "composer.json", composer); err != nil {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment. dependencies are installed
+	// using the composer image, which includes the
+	// extensions and utilities required by composer.
+	var image, composerImage string
+	if isContainerRuntime(pipeline) {
+		composerImage = "composer:2"
+		image = "php"
+		if v := minorVersion(composer.Require["php"]); v != "" {
+			image = "php:" + v + "-cli"
+		}
+	}
+
+	// environment variables passed to each step, and
+	// a command prefix to install the database driver.
+	var env map[string]string
+	var prefix string
+
+	// laravel applications require a database to run
+	// migrations and tests. sqlite databases do not
+	// require a service.
+	isLaravel := exists(fsys, "artisan")
+	if isLaravel {
+		env = map[string]string{
+			"APP_ENV": "testing",
+		}
+
+		if database, ok := laravelService(fsys); ok {
+			database = database.withDatabase("testing")
+			stage.Steps = append(stage.Steps, database.step())
+
+			env["DB_HOST"] = database.name
+			env["DB_PORT"] = database.port
+			env["DB_DATABASE"] = "testing"
+			env["DB_USERNAME"] = database.username
+			env["DB_PASSWORD"] = database.password
+			if database.name == "mysql" {
+				env["DB_CONNECTION"] = "mysql"
+				prefix = "docker-php-ext-install pdo_mysql && "
+			} else {
+				env["DB_CONNECTION"] = "pgsql"
+				prefix = "apt-get update && apt-get install -y libpq-dev && docker-php-ext-install pdo_pgsql && "
+			}
+			if image == "" {
+				prefix = ""
+			}
+		}
+	}
+
+	// add the composer install step
+	stage.Steps = append(stage.Steps, createScriptStep(composerImage,
+		"composer_install",
+		"composer install --no-interaction --prefer-dist --ignore-platform-reqs",
+	))
+
+	var steps []*spec.Step
+
+	// add the artisan migrate step
+	if isLaravel {
+		steps = append(steps, createScriptStep(image,
+			"artisan_migrate",
+			prefix+"cp -n .env.example .env; php artisan key:generate && php artisan migrate --force",
+		))
+	}
+
+	// add the steps for the well-known tools, preferring
+	// the command defined in the composer scripts.
+	for _, tool := range phpTools {
+		command := composer.script(tool.binary)
+		if command == "" && composer.RequireDev[tool.pkg] != "" {
+			command = tool.command
+		}
+		if command == "" {
+			continue
+		}
+		steps = append(steps, createScriptStep(image,
+			tool.name,
+			prefix+command,
+		))
+	}
+
+	for _, step := range steps {
+		step.Run.Env = env
+	}
+	stage.Steps = append(stage.Steps, steps...)
+
+	return nil
+}
+
+// helper function returns the database service for the
+// laravel application, based on the default connection in
+// the .env.example file, defaulting to mysql. Returns false
+// if the application uses sqlite, which does not require
+// a service.
+func laravelService(fsys fs.FS) (service, bool) {
+	data, _ := read(fsys, ".env.example")
+	switch {
+	case bytes.Contains(data, []byte("DB_CONNECTION=sqlite")):
+		return service{}, false
+	case bytes.Contains(data, []byte("DB_CONNECTION=pgsql")):
+		return postgresService, true
+	default:
+		return mysqlService, true
+	}
+}
+
+// represents the composer.json file format.
+type composerJson struct {
+	Require    map[string]string      `json:"require"`
+	RequireDev map[string]string      `json:"require-dev"`
+	Scripts    map[string]interface{} `json:"scripts"`
+}
+
+// script returns the first composer script command that
+// invokes the named tool, rewritten to use the tool binary
+// in the vendor/bin directory. Scripts are defined as a
+// single command or a list of commands.
+func (c *composerJson) script(binary string) string {
+	var names []string
+	for name := range c.Scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var commands []interface{}
+		switch v := c.Scripts[name].(type) {
+		case string:
+			commands = append(commands, v)
+		case []interface{}:
+			commands = v
+		}
+		for _, command := range commands {
+			command, _ := command.(string)
+			command = strings.TrimPrefix(command, "vendor/bin/")
+			if command == binary || strings.HasPrefix(command, binary+" ") {
+				return "vendor/bin/" + command
+			}
+		}
+	}
+	return ""
+}
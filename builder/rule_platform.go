@@ -16,6 +16,8 @@ package builder
 
 import (
 	"io/fs"
+	"path"
+	"strings"
 
 	spec "github.com/bradrydzewski/spec/yaml"
 )
@@ -38,5 +40,22 @@ func ConfigurePlatform(fsys fs.FS, pipeline *spec.Pipeline) error {
 // helper function returns true if the project has an xcode directory
 // in the root or a subdirectory of the repository.
 func isXcode(workspace fs.FS) bool {
-	return match(workspace, "*.xcodeproj") || match(workspace, "*/*.xcodeproj")
+	return match(workspace, "*.xcodeproj") ||
+		match(workspace, "*/*.xcodeproj") ||
+		len(xcodeWorkspaces(workspace)) != 0
+}
+
+// helper function returns the xcode workspaces in the root or a
+// subdirectory of the repository. The workspaces embedded in
+// xcode projects are ignored.
+func xcodeWorkspaces(workspace fs.FS) []string {
+	var names []string
+	for _, pattern := range []string{"*.xcworkspace", "*/*.xcworkspace"} {
+		for _, name := range glob(workspace, pattern) {
+			if !strings.HasSuffix(path.Dir(name), ".xcodeproj") {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
 }
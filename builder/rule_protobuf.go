@@ -0,0 +1,88 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// ConfigureProtobuf configures buf steps to lint, check for
+// breaking changes and generate code from protobuf files.
+func ConfigureProtobuf(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	// check for the buf configuration files, or protobuf
+	// files anywhere in the repository. buf uses the
+	// default configuration if not configured.
+	if !exists(fsys, "buf.yaml") &&
+		!exists(fsys, "buf.work.yaml") &&
+		!exists(fsys, "buf.gen.yaml") &&
+		len(walk(fsys, "*.proto")) == 0 {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment. the breaking change and
+	// generate steps require git, which is not included
+	// in the buf image, so buf is installed in an alpine
+	// image for these steps.
+	var image, gitImage, setup string
+	if isContainerRuntime(pipeline) {
+		image = "bufbuild/buf"
+		gitImage = "alpine/git"
+		setup = "wget -qO /usr/local/bin/buf https://github.com/bufbuild/buf/releases/latest/download/buf-Linux-x86_64" +
+			" && chmod +x /usr/local/bin/buf && "
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"buf_lint",
+		"buf lint",
+	))
+
+	// compare against the default branch, which must be
+	// fetched since the repository may be a shallow or
+	// single branch clone.
+	branch := gitDefaultBranch(fsys)
+	ref := "refs/remotes/origin/" + branch
+	stage.Steps = append(stage.Steps, createScriptStep(gitImage,
+		"buf_breaking",
+		setup+"git fetch origin "+quote(branch+":"+ref)+
+			" && buf breaking --against "+quote(".git#ref="+ref),
+	))
+
+	// generate the code and fail if the generated code
+	// committed to the repository is out of date.
+	if exists(fsys, "buf.gen.yaml") {
+		stage.Steps = append(stage.Steps, createScriptStep(gitImage,
+			"buf_generate",
+			setup+"buf generate && git diff --exit-code",
+		))
+	}
+
+	return nil
+}
+
+// helper function returns the default branch of the origin
+// remote, defaulting to main.
+func gitDefaultBranch(fsys fs.FS) string {
+	data, _ := read(fsys, ".git/refs/remotes/origin/HEAD")
+	if ref := strings.TrimSpace(string(data)); strings.HasPrefix(ref, "ref: refs/remotes/origin/") {
+		return strings.TrimPrefix(ref, "ref: refs/remotes/origin/")
+	}
+	return "main"
+}
@@ -0,0 +1,85 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// ConfigurePulumi configures a Pulumi preview step for each
+// stack in the Pulumi project.
+func ConfigurePulumi(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	// parse the Pulumi.yaml file
+	project := new(pulumiYaml)
+	if err := unmarshalYaml(fsys, "Pulumi.yaml", project); err != nil {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment. pulumi publishes an
+	// image for each language runtime.
+	var image string
+	if isContainerRuntime(pipeline) {
+		switch runtime := project.runtime(); runtime {
+		case "nodejs", "python", "go", "dotnet", "java":
+			image = "pulumi/pulumi-" + runtime
+		default:
+			image = "pulumi/pulumi"
+		}
+	}
+
+	// preview each stack configured in the repository,
+	// or the default stack if none are configured.
+	stacks := glob(fsys, "Pulumi.*.yaml")
+	if len(stacks) == 0 {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"pulumi_preview",
+			"pulumi install && pulumi preview",
+		))
+		return nil
+	}
+	for _, name := range stacks {
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "Pulumi."), ".yaml")
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"pulumi_preview_"+slug(name),
+			"pulumi install && pulumi preview --stack "+quote(name),
+		))
+	}
+	return nil
+}
+
+// represents the Pulumi.yaml file format.
+type pulumiYaml struct {
+	Name    string      `json:"name"`
+	Runtime interface{} `json:"runtime"`
+}
+
+// runtime returns the runtime name, which is defined as a
+// string, or as an object with a name field.
+func (p *pulumiYaml) runtime() string {
+	switch v := p.Runtime.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		name, _ := v["name"].(string)
+		return name
+	}
+	return ""
+}
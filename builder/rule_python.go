@@ -15,16 +15,245 @@
 package builder
 
 import (
+	"bytes"
 	"io/fs"
+	"strings"
 
 	spec "github.com/bradrydzewski/spec/yaml"
 )
 
 // ConfigurePython configures a Python step.
 func ConfigurePython(fsys fs.FS, pipeline *spec.Pipeline) error {
-	// stage := pipeline.Stages[0]
+	stage := pipeline.Stages[0]
 
-	// TODO
+	// check for the well-known python project files.
+	requirements := glob(fsys, "requirements*.txt")
+	if len(requirements) == 0 &&
+		!exists(fsys, "setup.py") &&
+		!exists(fsys, "setup.cfg") &&
+		!exists(fsys, "pyproject.toml") &&
+		!exists(fsys, "Pipfile") {
+		return nil
+	}
+
+	// parse the pyproject.toml file, if exists.
+	pyproject, _ := readToml(fsys, "pyproject.toml")
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "python:" + pythonVersion(fsys, pyproject)
+	}
+
+	// each step runs in a separate container and only the
+	// workspace is shared between steps. the package manager
+	// is installed in every step, and the virtual environment
+	// is created inside the workspace.
+	//
+	// setup is prepended to every command, and prefix is
+	// prepended to commands that need to run inside the
+	// virtual environment (e.g. poetry run pytest).
+	var setup, prefix string
+	var env map[string]string
+
+	var steps []*spec.Step
+
+	// add the install step for the package manager
+	switch pythonPackageManager(fsys, pyproject) {
+	case "poetry":
+		setup, prefix = "pip install poetry && ", "poetry run "
+		env = map[string]string{"POETRY_VIRTUALENVS_IN_PROJECT": "true"}
+		steps = append(steps, createScriptStep(image,
+			"poetry_install",
+			setup+"poetry install",
+		))
+	case "pdm":
+		setup, prefix = "pip install pdm && ", "pdm run "
+		env = map[string]string{"PDM_VENV_IN_PROJECT": "true"}
+		steps = append(steps, createScriptStep(image,
+			"pdm_install",
+			setup+"pdm install",
+		))
+	case "hatch":
+		setup, prefix = "pip install hatch && ", "hatch run "
+		env = map[string]string{"HATCH_ENV_TYPE_VIRTUAL_PATH": ".venv"}
+		steps = append(steps, createScriptStep(image,
+			"hatch_install",
+			setup+"hatch env create",
+		))
+	case "pipenv":
+		setup, prefix = "pip install pipenv && ", "pipenv run "
+		env = map[string]string{"PIPENV_VENV_IN_PROJECT": "1"}
+		command := setup + "pipenv install --dev"
+		if exists(fsys, "Pipfile.lock") {
+			command = command + " --deploy"
+		}
+		steps = append(steps, createScriptStep(image,
+			"pipenv_install",
+			command,
+		))
+	default:
+		setup = ". .venv/bin/activate && "
+		commands := []string{"python -m venv .venv", ". .venv/bin/activate"}
+		for _, name := range requirements {
+			commands = append(commands, "pip install -r "+name)
+		}
+		// the project is only installed if it is packaged,
+		// since the pyproject.toml file may only include
+		// the tool configuration.
+		if exists(fsys, "setup.py") ||
+			pyproject.has("project") ||
+			pyproject.has("build-system") {
+			commands = append(commands, "pip install -e .")
+		}
+		steps = append(steps, createScriptStep(image,
+			"pip_install",
+			strings.Join(commands, " && "),
+		))
+	}
+
+	// helper function returns the command used to run the
+	// named tool. If the project does not use a package
+	// manager the tool is installed in the virtual
+	// environment before it is invoked.
+	tool := func(name, command string) string {
+		if prefix == "" {
+			return setup + "pip install " + name + " && " + command
+		}
+		return setup + prefix + command
+	}
+
+	setupcfg, _ := read(fsys, "setup.cfg")
+	toxini, _ := read(fsys, "tox.ini")
+
+	// add the ruff step if configured
+	if exists(fsys, "ruff.toml") ||
+		exists(fsys, ".ruff.toml") ||
+		pyproject.has("tool.ruff") {
+		steps = append(steps, createScriptStep(image,
+			"ruff",
+			tool("ruff", "ruff check ."),
+		))
+	}
+
+	// add the flake8 step if configured
+	if exists(fsys, ".flake8") ||
+		bytes.Contains(setupcfg, []byte("[flake8]")) ||
+		bytes.Contains(toxini, []byte("[flake8]")) {
+		steps = append(steps, createScriptStep(image,
+			"flake8",
+			tool("flake8", "flake8"),
+		))
+	}
+
+	// add the black step if configured
+	if pyproject.has("tool.black") {
+		steps = append(steps, createScriptStep(image,
+			"black",
+			tool("black", "black --check ."),
+		))
+	}
+
+	// add the test step. tox takes precedence since it
+	// manages its own test environments, followed by
+	// pytest and finally unittest.
+	switch {
+	case len(toxini) != 0 || pyproject.raw("tool.tox", "legacy_tox_ini") != "":
+		steps = append(steps, createScriptStep(image,
+			"tox",
+			"pip install tox && tox",
+		))
+	case isPytest(fsys, pyproject, setupcfg, requirements):
+		steps = append(steps, createScriptStep(image,
+			"pytest",
+			tool("pytest", "pytest"),
+		))
+	case exists(fsys, "tests") || exists(fsys, "test"):
+		steps = append(steps, createScriptStep(image,
+			"unittest",
+			setup+prefix+"python -m unittest discover",
+		))
+	}
+
+	for _, step := range steps {
+		step.Run.Env = env
+	}
+	stage.Steps = append(stage.Steps, steps...)
 
 	return nil
 }
+
+// helper function returns the python package manager used
+// by the project, defaulting to pip.
+func pythonPackageManager(fsys fs.FS, pyproject tomlFile) string {
+	backend := pyproject.get("build-system", "build-backend")
+	switch {
+	case exists(fsys, "poetry.lock"),
+		pyproject.has("tool.poetry"),
+		strings.HasPrefix(backend, "poetry"):
+		return "poetry"
+	case exists(fsys, "pdm.lock"),
+		pyproject.has("tool.pdm"),
+		strings.HasPrefix(backend, "pdm"):
+		return "pdm"
+	case pyproject.has("tool.hatch"),
+		strings.HasPrefix(backend, "hatchling"):
+		return "hatch"
+	case exists(fsys, "Pipfile"):
+		return "pipenv"
+	default:
+		return "pip"
+	}
+}
+
+// helper function returns the python version from the
+// .python-version file or the requires-python field,
+// defaulting to python 3.
+func pythonVersion(fsys fs.FS, pyproject tomlFile) string {
+	if v := minorVersion(readVersion(fsys, ".python-version")); v != "" {
+		return v
+	}
+	if v := pythonLowerBound(pyproject.get("project", "requires-python")); v != "" {
+		return v
+	}
+	if v := pythonLowerBound(pyproject.get("tool.poetry.dependencies", "python")); v != "" {
+		return v
+	}
+	return "3"
+}
+
+// helper function returns the major and minor version of the
+// lower bound of the version constraint (e.g. <4,>=3.9).
+// Upper bound and exclusion clauses are ignored.
+func pythonLowerBound(constraint string) string {
+	for _, clause := range strings.FieldsFunc(constraint, func(r rune) bool {
+		return r == ',' || r == '|'
+	}) {
+		clause = strings.TrimSpace(clause)
+		if strings.HasPrefix(clause, "<") || strings.HasPrefix(clause, "!=") {
+			continue
+		}
+		if v := minorVersion(clause); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// helper function returns true if the project uses pytest.
+func isPytest(fsys fs.FS, pyproject tomlFile, setupcfg []byte, requirements []string) bool {
+	if exists(fsys, "pytest.ini") ||
+		exists(fsys, "conftest.py") ||
+		pyproject.has("tool.pytest") ||
+		bytes.Contains(setupcfg, []byte("[tool:pytest]")) {
+		return true
+	}
+	for _, name := range append(requirements, "pyproject.toml", "Pipfile") {
+		data, _ := read(fsys, name)
+		if bytes.Contains(data, []byte("pytest")) {
+			return true
+		}
+	}
+	return false
+}
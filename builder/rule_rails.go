@@ -15,12 +15,21 @@
 package builder
 
 import (
-	"bytes"
 	"io/fs"
+	"regexp"
 
 	spec "github.com/bradrydzewski/spec/yaml"
 )
 
+// regular expressions to extract the database adapters from
+// the database.yml file, and the database gems from the
+// Gemfile. The database.yml file is not parsed as yaml since
+// it commonly contains erb tags.
+var (
+	railsAdapter = regexp.MustCompile(`(?m)^\s*adapter:\s*["']?(\w+)`)
+	railsGem     = regexp.MustCompile(`(?m)^\s*gem\s+["'](pg|mysql2|trilogy|sqlite3|redis)["']`)
+)
+
 // ConfigureRails configures a Ruby on Rails step.
 func ConfigureRails(fsys fs.FS, pipeline *spec.Pipeline) error {
 	stage := pipeline.Stages[0]
@@ -29,7 +38,7 @@ func ConfigureRails(fsys fs.FS, pipeline *spec.Pipeline) error {
 	// execution environment.
 	var image string
 	if isContainerRuntime(pipeline) {
-		image = "ruby"
+		image = rubyImage(fsys)
 	}
 
 	// check for a ruby gemfile
@@ -37,31 +46,83 @@ func ConfigureRails(fsys fs.FS, pipeline *spec.Pipeline) error {
 
 		// ignore gemfiles that do not contain the
 		// rails dependency
-		gemfile, _ := read(fsys, "Gemfile")
-		if !bytes.Contains(gemfile, []byte("'rails'")) {
+		if !isRails(fsys) {
 			return nil
 		}
+		gemfile, _ := read(fsys, "Gemfile")
 
-		stage.Steps = append(stage.Steps, createScriptStep(image,
-			"bundle_install",
-			"bundle install — jobs=3 — retry=3",
-		))
-
-		stage.Steps = append(stage.Steps, createScriptStep(image,
-			"bundle_db_create",
-			"bundle exec rake db:create",
-		))
+		// environment variables passed to each step,
+		// including the service connection urls. gems are
+		// installed to the workspace, since the default gem
+		// path is not shared between steps.
+		env := map[string]string{
+			"RAILS_ENV":   "test",
+			"BUNDLE_PATH": "vendor/bundle",
+		}
 
-		stage.Steps = append(stage.Steps, createScriptStep(image,
-			"bundle_db_migrate",
-			"bundle exec rake db:migrate",
-		))
+		// add the service containers required by the
+		// database adapters and gems.
+		for _, service := range railsServices(fsys, gemfile) {
+			stage.Steps = append(stage.Steps, service.step())
+			switch service.name {
+			case "postgres":
+				env["DATABASE_URL"] = service.url("postgres", "test")
+			case "mysql":
+				env["DATABASE_URL"] = service.url("mysql2", "test")
+			case "redis":
+				env["REDIS_URL"] = service.url("redis", "0")
+			}
+		}
 
-		stage.Steps = append(stage.Steps, createScriptStep(image,
-			"bundle_rspec",
-			"bundle exec rspec",
-		))
+		steps := []*spec.Step{
+			createScriptStep(image,
+				"bundle_install",
+				"bundle install --jobs=3 --retry=3",
+			),
+			createScriptStep(image,
+				"bundle_db_create",
+				"bundle exec rake db:create",
+			),
+			createScriptStep(image,
+				"bundle_db_migrate",
+				"bundle exec rake db:migrate",
+			),
+			createScriptStep(image,
+				"bundle_rspec",
+				"bundle exec rspec",
+			),
+		}
+		for _, step := range steps {
+			step.Run.Env = env
+		}
+		stage.Steps = append(stage.Steps, steps...)
 	}
 
 	return nil
 }
+
+// helper function returns the service containers required
+// by the database adapters defined in the database.yml file
+// and the database gems defined in the Gemfile.
+func railsServices(fsys fs.FS, gemfile []byte) []service {
+	names := map[string]bool{}
+	database, _ := read(fsys, "config/database.yml")
+	for _, match := range railsAdapter.FindAllSubmatch(database, -1) {
+		names[string(match[1])] = true
+	}
+	for _, match := range railsGem.FindAllSubmatch(gemfile, -1) {
+		names[string(match[1])] = true
+	}
+
+	var services []service
+	switch {
+	case names["postgresql"], names["postgis"], names["pg"]:
+		services = append(services, postgresService)
+	case names["mysql2"], names["trilogy"]:
+		services = append(services, mysqlService)
+	}
+	if names["redis"] {
+		services = append(services, redisService)
+	}
+	return services
+}
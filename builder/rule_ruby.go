@@ -17,10 +17,21 @@ package builder
 import (
 	"bytes"
 	"io/fs"
+	"regexp"
+	"strings"
 
 	spec "github.com/bradrydzewski/spec/yaml"
 )
 
+// regular expressions to extract the ruby version from the
+// Gemfile, and to detect the rails dependency in the Gemfile
+// and Gemfile.lock files.
+var (
+	rubyDirective = regexp.MustCompile(`(?m)^\s*ruby\s+["']([^"']+)["']`)
+	rubyRailsGem  = regexp.MustCompile(`(?m)^\s*gem\s+["']rails["']`)
+	rubyRailsLock = regexp.MustCompile(`(?m)^\s+rails(?: \(|$)`)
+)
+
 // ConfigureRuby configures a Ruby on Rails step.
 func ConfigureRuby(fsys fs.FS, pipeline *spec.Pipeline) error {
 	stage := pipeline.Stages[0]
@@ -29,20 +40,23 @@ func ConfigureRuby(fsys fs.FS, pipeline *spec.Pipeline) error {
 	// execution environment.
 	var image string
 	if isContainerRuntime(pipeline) {
-		image = "ruby"
+		image = rubyImage(fsys)
+	}
+
+	// ignore ruby on rails.  we will handle rails
+	// in a separate rule.
+	if isRails(fsys) {
+		return nil
 	}
 
+	// the steps added by this rule, which install the
+	// gems to the workspace.
+	start := len(stage.Steps)
+
 	// generate pipeline steps for rakefiles
 	if exists(fsys, "Rakefile") {
 		rakefile, _ := read(fsys, "Rakefile")
 
-		// ignore ruby on rails.  we will handle rails
-		// in a separate rule.
-		gemfile, _ := read(fsys, "Gemfile")
-		if bytes.Contains(gemfile, []byte("'rails'")) {
-			return nil
-		}
-
 		// bundle install
 		stage.Steps = append(stage.Steps, createScriptStep(image,
 			"bundle_install",
@@ -79,6 +93,8 @@ func ConfigureRuby(fsys fs.FS, pipeline *spec.Pipeline) error {
 			))
 		}
 
+		configureRubocop(fsys, stage, image)
+		configureBundlePath(stage.Steps[start:])
 		return nil
 	}
 
@@ -87,5 +103,108 @@ func ConfigureRuby(fsys fs.FS, pipeline *spec.Pipeline) error {
 	// do not use rakefiles.
 	//
 
+	gemspecs := glob(fsys, "*.gemspec")
+	hasGemfile := exists(fsys, "Gemfile")
+	if !hasGemfile && len(gemspecs) == 0 {
+		return nil
+	}
+
+	// bundler requires a Gemfile, so gems without a
+	// Gemfile are only built.
+	if hasGemfile {
+		// bundle install
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"bundle_install",
+			"bundle install",
+		))
+
+		// add the rspec or minitest step
+		switch {
+		case exists(fsys, ".rspec"), exists(fsys, "spec"):
+			stage.Steps = append(stage.Steps, createScriptStep(image,
+				"bundle_rspec",
+				"bundle exec rspec",
+			))
+		case exists(fsys, "test"):
+			stage.Steps = append(stage.Steps, createScriptStep(image,
+				"bundle_minitest",
+				`bundle exec ruby -Ilib -Itest -e "Dir.glob('./test/**/*_test.rb').each { |f| require f }"`,
+			))
+		}
+	}
+
+	// add the gem build step
+	if len(gemspecs) != 0 {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"gem_build",
+			"gem build "+quote(gemspecs[0]),
+		))
+	}
+
+	if hasGemfile {
+		configureRubocop(fsys, stage, image)
+	}
+	configureBundlePath(stage.Steps[start:])
 	return nil
 }
+
+// helper function configures the rubocop step, if the
+// project includes a rubocop configuration file.
+func configureRubocop(fsys fs.FS, stage *spec.Stage, image string) {
+	if exists(fsys, ".rubocop.yml") {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"bundle_rubocop",
+			"bundle exec rubocop",
+		))
+	}
+}
+
+// helper function configures the steps to install the gems
+// to the workspace, since the default gem path is not shared
+// between steps.
+func configureBundlePath(steps []*spec.Step) {
+	for _, step := range steps {
+		if step.Run.Env == nil {
+			step.Run.Env = map[string]string{}
+		}
+		step.Run.Env["BUNDLE_PATH"] = "vendor/bundle"
+	}
+}
+
+// helper function returns true if the project depends on
+// rails, according to the Gemfile.lock or Gemfile.
+func isRails(fsys fs.FS) bool {
+	if lockfile, err := read(fsys, "Gemfile.lock"); err == nil {
+		return rubyRailsLock.Match(lockfile)
+	}
+	gemfile, _ := read(fsys, "Gemfile")
+	return rubyRailsGem.Match(gemfile)
+}
+
+// helper function returns the ruby image for the version
+// defined in the .ruby-version file, the .tool-versions
+// file or the Gemfile ruby directive.
+func rubyImage(fsys fs.FS) string {
+	versions := []string{
+		readVersion(fsys, ".ruby-version"),
+		toolVersion(fsys, "ruby"),
+	}
+	gemfile, _ := read(fsys, "Gemfile")
+	if match := rubyDirective.FindSubmatch(gemfile); match != nil {
+		versions = append(versions, string(match[1]))
+	}
+
+	for _, v := range versions {
+		// ignore alternate ruby implementations, such
+		// as jruby or truffleruby.
+		if strings.HasPrefix(v, "jruby") ||
+			strings.HasPrefix(v, "truffleruby") ||
+			strings.HasPrefix(v, "mruby") {
+			continue
+		}
+		if v := minorVersion(v); v != "" {
+			return "ruby:" + v
+		}
+	}
+	return "ruby"
+}
@@ -15,16 +15,145 @@
 package builder
 
 import (
+	"bytes"
 	"io/fs"
+	"path"
+	"strings"
 
 	spec "github.com/bradrydzewski/spec/yaml"
 )
 
 // ConfigureRust configures a Rust step.
 func ConfigureRust(fsys fs.FS, pipeline *spec.Pipeline) error {
-	// stage := pipeline.Stages[0]
+	stage := pipeline.Stages[0]
 
-	// TODO
+	// check for the Cargo.toml file.
+	if !exists(fsys, "Cargo.toml") {
+		return nil
+	}
+
+	// parse the Cargo.toml file
+	manifest, err := readToml(fsys, "Cargo.toml")
+	if err != nil {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = rustImage(fsys)
+	}
+
+	// workspaces are built using the --workspace flag
+	// unless the members are explicitly listed, in which
+	// case we add steps for each member package.
+	var flags string
+	members := rustMembers(fsys, manifest)
+	if manifest.has("workspace") && len(members) == 0 {
+		flags = " --workspace"
+	}
+
+	// add the cargo build step
+	if len(members) == 0 {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"cargo_build",
+			"cargo build"+flags,
+		))
+	}
+	for _, member := range members {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"cargo_build_"+strings.ReplaceAll(member, "-", "_"),
+			"cargo build -p "+member,
+		))
+	}
+
+	// add the cargo test step
+	if len(members) == 0 {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"cargo_test",
+			"cargo test"+flags,
+		))
+	}
+	for _, member := range members {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			"cargo_test_"+strings.ReplaceAll(member, "-", "_"),
+			"cargo test -p "+member,
+		))
+	}
+
+	// add the cargo clippy step
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"cargo_clippy",
+		"rustup component add clippy && cargo clippy"+flags+" --all-targets -- -D warnings",
+	))
+
+	// add the cargo fmt step
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"cargo_fmt",
+		"rustup component add rustfmt && cargo fmt --all --check",
+	))
 
 	return nil
 }
+
+// helper function returns the package names of the explicitly
+// listed workspace members. It returns nil if the manifest
+// is not a workspace, or if the members cannot be resolved
+// (e.g. the members are listed using glob patterns).
+func rustMembers(fsys fs.FS, manifest tomlFile) []string {
+	members := manifest.array("workspace", "members")
+	if len(members) == 0 {
+		return nil
+	}
+
+	var names []string
+	if name := manifest.get("package", "name"); name != "" {
+		names = append(names, name)
+	}
+	for _, member := range members {
+		if strings.ContainsAny(member, "*?[") {
+			return nil
+		}
+		file, err := readToml(fsys, path.Join(member, "Cargo.toml"))
+		if err != nil {
+			return nil
+		}
+		name := file.get("package", "name")
+		if name == "" {
+			return nil
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// helper function returns the rust image for the toolchain
+// channel defined in the rust-toolchain.toml or the legacy
+// rust-toolchain file, defaulting to the latest stable.
+func rustImage(fsys fs.FS) string {
+	var channel string
+	for _, name := range []string{"rust-toolchain.toml", "rust-toolchain"} {
+		data, err := read(fsys, name)
+		if err != nil {
+			continue
+		}
+		// the legacy rust-toolchain file may contain
+		// either the channel name or toml.
+		if bytes.Contains(data, []byte("[toolchain]")) {
+			channel = parseToml(data).get("toolchain", "channel")
+		} else {
+			channel = readVersion(fsys, name)
+		}
+		break
+	}
+
+	switch {
+	case strings.HasPrefix(channel, "nightly"):
+		return "rustlang/rust:nightly"
+	case channel != "" && channel[0] >= '0' && channel[0] <= '9':
+		return "rust:" + channel
+	default:
+		return "rust:1"
+	}
+}
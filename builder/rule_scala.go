@@ -0,0 +1,100 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// ConfigureScala configures a Scala step for sbt and Mill
+// projects.
+func ConfigureScala(fsys fs.FS, pipeline *spec.Pipeline) error {
+	switch {
+	case exists(fsys, "build.sbt"):
+		configureSbt(fsys, pipeline)
+	case exists(fsys, "build.sc"):
+		configureMill(fsys, pipeline)
+	}
+	return nil
+}
+
+// helper function configures the sbt steps.
+func configureSbt(fsys fs.FS, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	// check if we should use a container-based
+	// execution environment. the jdk image does not
+	// include sbt, so the sbt-extras launcher is
+	// downloaded in each step, unless committed to the
+	// repository. the launcher uses the sbt version
+	// pinned in the project/build.properties file.
+	var image, setup string
+	if isContainerRuntime(pipeline) {
+		image = jdkImage(jdkVersion(fsys))
+		if !exists(fsys, "sbt") {
+			setup = "curl -fsSL https://raw.githubusercontent.com/dwijnand/sbt-extras/master/sbt -o sbt && chmod +x sbt && "
+		}
+	}
+
+	command := "sbt"
+	if image != "" || exists(fsys, "sbt") {
+		command = "./sbt"
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"sbt_compile",
+		setup+command+" Test/compile",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"sbt_test",
+		setup+command+" test",
+	))
+}
+
+// helper function configures the mill steps.
+func configureMill(fsys fs.FS, pipeline *spec.Pipeline) {
+	stage := pipeline.Stages[0]
+
+	// check if we should use a container-based
+	// execution environment. the millw launcher is
+	// downloaded in each step, unless committed to the
+	// repository. the launcher uses the mill version
+	// pinned in the .mill-version file.
+	var image, setup string
+	if isContainerRuntime(pipeline) {
+		image = jdkImage(jdkVersion(fsys))
+		if !exists(fsys, "mill") {
+			setup = "curl -fsSL https://raw.githubusercontent.com/lefou/millw/main/millw -o mill && chmod +x mill && "
+		}
+	}
+
+	command := "mill"
+	if image != "" || exists(fsys, "mill") {
+		command = "./mill"
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"mill_compile",
+		setup+command+" __.compile",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"mill_test",
+		setup+command+" __.test",
+	))
+}
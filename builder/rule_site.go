@@ -0,0 +1,139 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// ConfigureSite configures a static site generator step for
+// Docusaurus, Hugo, MkDocs, Jekyll and Sphinx, and a link
+// checker step for the generated site.
+func ConfigureSite(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	var siteImage, name, command, output string
+	switch {
+	case match(fsys, "docusaurus.config.*"):
+		json := new(packageJson)
+		unmarshal(fsys, "package.json", json)
+		manager := nodePackageManager(fsys, json)
+		siteImage = "node"
+		if v := nodeVersion(fsys, json); v != "" {
+			siteImage = "node:" + v
+		}
+		// docusaurus fails the build on broken links
+		// by default.
+		name = "docusaurus_build"
+		command = nodeInstall(fsys, manager, json) + " && " + nodeExec(manager) + " docusaurus build"
+		output = "build"
+
+	case isHugo(fsys):
+		siteImage = "ghcr.io/gohugoio/hugo"
+		name = "hugo_build"
+		command = "hugo --gc --minify --panicOnWarning"
+		output = "public"
+
+	case exists(fsys, "mkdocs.yml"):
+		pyproject, _ := readToml(fsys, "pyproject.toml")
+		siteImage = "python:" + pythonVersion(fsys, pyproject)
+		name = "mkdocs_build"
+		command = pythonDocsInstall(fsys, "mkdocs") + "mkdocs build --strict"
+		output = "site"
+
+	case exists(fsys, "_config.yml") && isJekyll(fsys):
+		siteImage = rubyImage(fsys)
+		name = "jekyll_build"
+		command = "bundle install && bundle exec jekyll build --strict_front_matter"
+		output = "_site"
+
+	case sphinxDir(fsys) != "":
+		dir := sphinxDir(fsys)
+		pyproject, _ := readToml(fsys, "pyproject.toml")
+		siteImage = "python:" + pythonVersion(fsys, pyproject)
+		name = "sphinx_build"
+		output = path.Join(dir, "_build", "html")
+		command = pythonDocsInstall(fsys, "sphinx") +
+			"sphinx-build -W --keep-going -b html " + quote(dir) + " " + quote(output)
+
+	default:
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment.
+	var image, linkImage string
+	if isContainerRuntime(pipeline) {
+		image = siteImage
+		linkImage = "lycheeverse/lychee"
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		name,
+		command,
+	))
+
+	// check the links in the generated site. external
+	// links are not checked, since they are unreliable
+	// and slow the pipeline.
+	stage.Steps = append(stage.Steps, createScriptStep(linkImage,
+		"lychee",
+		"lychee --offline --no-progress "+quote(output),
+	))
+
+	return nil
+}
+
+// helper function returns true if the project is a hugo
+// site, configured with a hugo configuration file, or a
+// legacy config file and a themes directory.
+func isHugo(fsys fs.FS) bool {
+	return match(fsys, "hugo.*") ||
+		(match(fsys, "config.*") && exists(fsys, "themes"))
+}
+
+// helper function returns true if the Gemfile includes the
+// jekyll gem.
+func isJekyll(fsys fs.FS) bool {
+	data, _ := read(fsys, "Gemfile")
+	return bytes.Contains(data, []byte("jekyll"))
+}
+
+// helper function returns the sphinx source directory, which
+// contains the conf.py file.
+func sphinxDir(fsys fs.FS) string {
+	for _, dir := range []string{"docs", "docs/source", "doc", "."} {
+		if exists(fsys, path.Join(dir, "conf.py")) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// helper function returns the command to install the python
+// documentation tool, using the documentation requirements
+// file if exists.
+func pythonDocsInstall(fsys fs.FS, pkg string) string {
+	for _, name := range []string{"docs/requirements.txt", "requirements-docs.txt", "requirements.txt"} {
+		if exists(fsys, name) {
+			return "pip install -r " + name + " && "
+		}
+	}
+	return "pip install " + pkg + " && "
+}
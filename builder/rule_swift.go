@@ -15,16 +15,156 @@
 package builder
 
 import (
+	"bytes"
 	"io/fs"
+	"path"
+	"regexp"
+	"strings"
 
 	spec "github.com/bradrydzewski/spec/yaml"
 )
 
+// regular expression to extract the swift tools version
+// from the Package.swift file.
+var swiftTools = regexp.MustCompile(`swift-tools-version:\s*(\d+\.\d+)`)
+
 // ConfigureSwift configures a Swift step.
 func ConfigureSwift(fsys fs.FS, pipeline *spec.Pipeline) error {
-	// stage := pipeline.Stages[0]
+	stage := pipeline.Stages[0]
+
+	// xcode projects and workspaces are built with
+	// xcodebuild. the platform rule has already switched
+	// the stage to mac hardware.
+	if isXcode(fsys) {
+		configureXcode(fsys, stage)
+		return nil
+	}
+
+	// check for the Package.swift file.
+	if !exists(fsys, "Package.swift") {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = "swift"
+		if v := swiftVersion(fsys); v != "" {
+			image = image + ":" + v
+		}
+	}
+
+	// add the swift build step
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"swift_build",
+		"swift build",
+	))
 
-	// TODO
+	// add the swift test step
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"swift_test",
+		"swift test",
+	))
 
 	return nil
 }
+
+// helper function configures the xcodebuild steps.
+func configureXcode(fsys fs.FS, stage *spec.Stage) {
+	// install cocoapods dependencies, which generates
+	// the xcode workspace used to build the project.
+	if exists(fsys, "Podfile") {
+		stage.Steps = append(stage.Steps, createScriptStep("",
+			"pod_install",
+			"pod install",
+		))
+	}
+
+	// prefer the xcode workspace, if exists, since it
+	// references the project and its dependencies.
+	flag, name := "-project", ""
+	if workspaces := xcodeWorkspaces(fsys); len(workspaces) != 0 {
+		flag, name = "-workspace", workspaces[0]
+	} else if projects := xcodeProjects(fsys); len(projects) != 0 {
+		name = projects[0]
+	} else {
+		return
+	}
+
+	command := "xcodebuild " + flag + " " + quote(name) +
+		" -scheme " + quote(xcodeScheme(fsys, name))
+
+	// ios projects are built for a generic simulator, and
+	// tested using the first available iphone simulator,
+	// since the installed simulators depend on the xcode
+	// version.
+	var build, test string
+	if isXcodeIOS(fsys) {
+		build = " -destination 'generic/platform=iOS Simulator'"
+		test = ` -destination "id=$(xcrun simctl list devices available | grep -m1 iPhone | grep -oE '[0-9A-F-]{36}')"`
+	}
+
+	// add the xcodebuild build step
+	stage.Steps = append(stage.Steps, createScriptStep("",
+		"xcodebuild_build",
+		command+build+" CODE_SIGNING_ALLOWED=NO build",
+	))
+
+	// add the xcodebuild test step
+	stage.Steps = append(stage.Steps, createScriptStep("",
+		"xcodebuild_test",
+		command+test+" CODE_SIGNING_ALLOWED=NO test",
+	))
+}
+
+// helper function returns the xcode projects in the root or a
+// subdirectory of the repository.
+func xcodeProjects(fsys fs.FS) []string {
+	return append(
+		glob(fsys, "*.xcodeproj"),
+		glob(fsys, "*/*.xcodeproj")...,
+	)
+}
+
+// helper function returns the name of the first shared scheme
+// in the xcode workspace or project, defaulting to the name
+// of the workspace or project.
+func xcodeScheme(fsys fs.FS, name string) string {
+	patterns := []string{
+		path.Join(name, "xcshareddata/xcschemes/*.xcscheme"),
+		path.Join(path.Dir(name), "*.xcodeproj/xcshareddata/xcschemes/*.xcscheme"),
+	}
+	for _, pattern := range patterns {
+		if matches := glob(fsys, pattern); len(matches) != 0 {
+			return strings.TrimSuffix(path.Base(matches[0]), ".xcscheme")
+		}
+	}
+	return strings.TrimSuffix(path.Base(name), path.Ext(name))
+}
+
+// helper function returns true if the xcode project targets
+// the ios sdk.
+func isXcodeIOS(fsys fs.FS) bool {
+	for _, name := range xcodeProjects(fsys) {
+		data, _ := read(fsys, path.Join(name, "project.pbxproj"))
+		if bytes.Contains(data, []byte("SDKROOT = iphoneos")) {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function returns the swift version from the
+// .swift-version file or the swift tools version in the
+// Package.swift file.
+func swiftVersion(fsys fs.FS) string {
+	if v := minorVersion(readVersion(fsys, ".swift-version")); v != "" {
+		return v
+	}
+	data, _ := read(fsys, "Package.swift")
+	if match := swiftTools.FindSubmatch(data); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
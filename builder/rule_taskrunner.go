@@ -0,0 +1,327 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"regexp"
+	"strings"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expressions to extract the targets from the
+// Makefile and the recipes from the justfile. Variable
+// assignments (e.g. VERSION := 1.0) are ignored.
+var (
+	makefileTarget = regexp.MustCompile(`(?m)^([A-Za-z0-9][\w./ -]*?)\s*::?(?:[^=]|$)`)
+	justfileRecipe = regexp.MustCompile(`(?m)^@?([A-Za-z0-9][\w-]*)[^:\n]*:(?:[^=]|$)`)
+)
+
+// well-known task runner targets, in execution order.
+var taskTargetNames = []string{"deps", "lint", "build", "test"}
+
+// ConfigureTaskRunner configures steps for the well-known
+// targets in the Makefile, justfile or Taskfile. The
+// language rules defer to the task runner when the project
+// wraps its build and test commands in these targets.
+func ConfigureTaskRunner(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	// the targets typically invoke the language
+	// toolchain, so the targets are ignored if there is
+	// no image for the project language.
+	runner, targets := taskTargets(fsys)
+	toolchain, install, ok := taskImage(fsys)
+	if len(targets) == 0 || !ok {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment. the task runner is
+	// installed in the image for the project language.
+	var image, setup string
+	if isContainerRuntime(pipeline) {
+		image, setup = toolchain, install
+		switch runner {
+		case "just":
+			setup += "curl -sSf https://just.systems/install.sh | bash -s -- --to /usr/local/bin && "
+		case "task":
+			setup += "sh -c \"$(curl -sSfL https://taskfile.dev/install.sh)\" -- -d -b /usr/local/bin && "
+		}
+	}
+
+	for _, target := range targets {
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			runner+"_"+slug(target),
+			setup+runner+" "+target,
+		))
+	}
+
+	return nil
+}
+
+// DeferToTaskRunner returns a rule that runs the language
+// rules, unless the project wraps its build and test
+// commands in task runner targets, which are configured by
+// the task runner rule.
+func DeferToTaskRunner(rules ...Rule) Rule {
+	return func(fsys fs.FS, pipeline *spec.Pipeline) error {
+		if isTaskRunner(fsys) {
+			return nil
+		}
+		for _, rule := range rules {
+			if err := rule(fsys, pipeline); err == SkipAll {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// helper function returns true if the project wraps its
+// build or test commands in well-known task runner targets,
+// and the targets can run in the image for the project
+// language.
+func isTaskRunner(fsys fs.FS) bool {
+	if _, _, ok := taskImage(fsys); !ok {
+		return false
+	}
+	_, targets := taskTargets(fsys)
+	for _, target := range targets {
+		switch target {
+		case "build", "test", "ci":
+			return true
+		}
+	}
+	return false
+}
+
+// helper function returns the task runner and its well-known
+// targets. The Taskfile takes precedence, followed by the
+// justfile and the Makefile. If a ci target exists it is
+// returned alone, since it typically aggregates the other
+// well-known targets.
+func taskTargets(fsys fs.FS) (string, []string) {
+	runners := []struct {
+		name  string
+		parse func(fs.FS) []string
+	}{
+		{"task", taskfileTargets},
+		{"just", justfileTargets},
+		{"make", makefileTargets},
+	}
+	for _, runner := range runners {
+		defined := map[string]bool{}
+		for _, target := range runner.parse(fsys) {
+			defined[target] = true
+		}
+		if defined["ci"] {
+			return runner.name, []string{"ci"}
+		}
+		var targets []string
+		for _, target := range taskTargetNames {
+			if defined[target] {
+				targets = append(targets, target)
+			}
+		}
+		if len(targets) != 0 {
+			return runner.name, targets
+		}
+	}
+	return "", nil
+}
+
+// helper function returns the targets defined in the
+// Makefile.
+func makefileTargets(fsys fs.FS) []string {
+	var targets []string
+	for _, name := range []string{"GNUmakefile", "makefile", "Makefile"} {
+		data, err := read(fsys, name)
+		if err != nil {
+			continue
+		}
+		for _, match := range makefileTarget.FindAllSubmatch(data, -1) {
+			targets = append(targets, strings.Fields(string(match[1]))...)
+		}
+		break
+	}
+	return targets
+}
+
+// helper function returns the recipes defined in the
+// justfile.
+func justfileTargets(fsys fs.FS) []string {
+	var targets []string
+	for _, name := range []string{"justfile", "Justfile", ".justfile"} {
+		data, err := read(fsys, name)
+		if err != nil {
+			continue
+		}
+		for _, match := range justfileRecipe.FindAllSubmatch(data, -1) {
+			targets = append(targets, string(match[1]))
+		}
+		break
+	}
+	return targets
+}
+
+// helper function returns the tasks defined in the Taskfile.
+func taskfileTargets(fsys fs.FS) []string {
+	var targets []string
+	for _, name := range []string{"Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml"} {
+		file := new(taskfile)
+		if err := unmarshalYaml(fsys, name, file); err != nil {
+			continue
+		}
+		for target := range file.Tasks {
+			targets = append(targets, target)
+		}
+		break
+	}
+	return targets
+}
+
+// helper function returns the image for the project language,
+// which is used to run the task runner targets, and the
+// command to install make and the language tools that are
+// not included in the image. Returns false if there is no
+// image for the project language. The gcc image is used by
+// default since it includes make.
+func taskImage(fsys fs.FS) (string, string, bool) {
+	const install = "apt-get update && apt-get install -y make && "
+	switch {
+	case isXcode(fsys):
+		// xcode projects run on mac hardware, which
+		// includes make.
+		return "", "", true
+	case exists(fsys, "go.mod"), exists(fsys, "go.work"):
+		return "golang:" + goVersion(fsys), "", true
+	case exists(fsys, "package.json"):
+		json := new(packageJson)
+		unmarshal(fsys, "package.json", json)
+		if v := nodeVersion(fsys, json); v != "" {
+			return "node:" + v, "", true
+		}
+		return "node", "", true
+	case exists(fsys, "Cargo.toml"):
+		return rustImage(fsys), "", true
+	case exists(fsys, "pyproject.toml"),
+		exists(fsys, "setup.py"),
+		match(fsys, "requirements*.txt"):
+		pyproject, _ := readToml(fsys, "pyproject.toml")
+		return "python:" + pythonVersion(fsys, pyproject), "", true
+	case exists(fsys, "pom.xml"):
+		pom := new(pomXml)
+		unmarshalXml(fsys, "pom.xml", pom)
+		return mavenImage(fsys, pom), install, true
+	case match(fsys, "build.gradle*"), match(fsys, "settings.gradle*"):
+		return gradleImage(fsys), install, true
+	case exists(fsys, "build.sbt"):
+		return jdkImage(jdkVersion(fsys)), install +
+			"curl -fsSL https://raw.githubusercontent.com/dwijnand/sbt-extras/master/sbt -o /usr/local/bin/sbt && chmod +x /usr/local/bin/sbt && ", true
+	case exists(fsys, "build.sc"):
+		return jdkImage(jdkVersion(fsys)), install +
+			"curl -fsSL https://raw.githubusercontent.com/lefou/millw/main/millw -o /usr/local/bin/mill && chmod +x /usr/local/bin/mill && ", true
+	case exists(fsys, "project.clj"), exists(fsys, "deps.edn"):
+		tool := "tools-deps"
+		if exists(fsys, "project.clj") {
+			tool = "lein"
+		}
+		if v := jdkVersion(fsys); v != "" {
+			return "clojure:temurin-" + v + "-" + tool, install, true
+		}
+		return "clojure:" + tool, install, true
+	case match(fsys, "*.sln"), len(dotnetProjects(fsys)) != 0:
+		if v := dotnetVersion(fsys, dotnetProjects(fsys)); v != "" {
+			return "mcr.microsoft.com/dotnet/sdk:" + v, install, true
+		}
+		return "mcr.microsoft.com/dotnet/sdk", install, true
+	case exists(fsys, "composer.json"):
+		// composer is not included in the php image.
+		setup := "apt-get update && apt-get install -y make git unzip && " +
+			"curl -sS https://getcomposer.org/installer | php -- --install-dir=/usr/local/bin --filename=composer && "
+		composer := new(composerJson)
+		unmarshal(fsys, "composer.json", composer)
+		if v := minorVersion(composer.Require["php"]); v != "" {
+			return "php:" + v + "-cli", setup, true
+		}
+		return "php", setup, true
+	case exists(fsys, "mix.exs"):
+		setup := "mix local.hex --force && mix local.rebar --force && "
+		mixexs, _ := read(fsys, "mix.exs")
+		if v := elixirVersion(fsys, mixexs); v != "" {
+			return "elixir:" + v, setup, true
+		}
+		return "elixir", setup, true
+	case exists(fsys, "rebar.config"):
+		if v := majorVersion(toolVersion(fsys, "erlang")); v != "" {
+			return "erlang:" + v, "", true
+		}
+		return "erlang", "", true
+	case exists(fsys, "Gemfile"):
+		return rubyImage(fsys), "", true
+	case exists(fsys, "pubspec.yaml"):
+		pubspec := new(pubspecYaml)
+		unmarshalYaml(fsys, "pubspec.yaml", pubspec)
+		if pubspec.isFlutter() {
+			return "ghcr.io/cirruslabs/flutter:" + flutterVersion(fsys), install, true
+		}
+		if v := minorVersion(pubspec.Environment["sdk"]); v != "" {
+			return "dart:" + v, install, true
+		}
+		return "dart", install, true
+	case exists(fsys, "Package.swift"):
+		if v := swiftVersion(fsys); v != "" {
+			return "swift:" + v, install, true
+		}
+		return "swift", install, true
+	case exists(fsys, "stack.yaml"), exists(fsys, "cabal.project"), match(fsys, "*.cabal"):
+		if v := ghcVersion(fsys, glob(fsys, "*.cabal")); v != "" {
+			return "haskell:" + v, install, true
+		}
+		return "haskell", install, true
+	case exists(fsys, "dune-project"), match(fsys, "*.opam"):
+		// the opam image includes make, and the tools
+		// installed in the opam switch are added to the
+		// path.
+		opam := glob(fsys, "*.opam")
+		setup := "opam install . --deps-only --with-test -y && eval $(opam env) && "
+		if len(opam) == 0 {
+			setup = "opam install dune -y && eval $(opam env) && "
+		}
+		if v := ocamlVersion(fsys, opam); v != "" {
+			return "ocaml/opam:debian-ocaml-" + v, setup, true
+		}
+		return "ocaml/opam", setup, true
+	case exists(fsys, "build.zig"):
+		// there is no zig image, and the zig release
+		// installed by the zig rule is not in the path.
+		return "", "", false
+	case exists(fsys, "CMakeLists.txt"):
+		return "gcc", cppInstall(fsys, cmakePackage, "CMakeLists.txt", "cmake"), true
+	case exists(fsys, "meson.build"):
+		return "gcc", cppInstall(fsys, mesonPackage, "meson.build", "meson", "ninja-build", "pkg-config"), true
+	case exists(fsys, "configure.ac"):
+		return "gcc", cppInstall(fsys, nil, "", "autoconf", "automake", "libtool", "pkg-config"), true
+	default:
+		return "gcc", "", true
+	}
+}
+
+// represents the Taskfile.yml file format.
+type taskfile struct {
+	Tasks map[string]interface{} `json:"tasks"`
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expressions to extract the required terraform
+// version and the local module sources from the terraform
+// configuration files.
+var (
+	terraformRequired = regexp.MustCompile(`required_version\s*=\s*"([^"]+)"`)
+	terraformModule   = regexp.MustCompile(`source\s*=\s*"(\.\.?/[^"]*)"`)
+)
+
+// ConfigureTerraform configures a Terraform or OpenTofu step
+// for each root module in the repository.
+func ConfigureTerraform(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	roots, required := terraformRoots(fsys)
+	if len(roots) == 0 {
+		return nil
+	}
+
+	// opentofu projects are detected by the version file
+	// or tofu-specific configuration files.
+	command := "terraform"
+	if exists(fsys, ".opentofu-version") ||
+		toolVersion(fsys, "opentofu") != "" ||
+		len(walk(fsys, "*.tofu")) != 0 {
+		command = "tofu"
+	}
+
+	// check if we should use a container-based
+	// execution environment.
+	var image string
+	if isContainerRuntime(pipeline) {
+		image = terraformImage(fsys, command, required)
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		command+"_fmt",
+		command+" fmt -check -recursive",
+	))
+
+	// initialize and validate each root module. the
+	// backend is not initialized, since the pipeline
+	// has no access to the remote state.
+	for _, root := range roots {
+		name, chdir := command, command
+		if root != "." {
+			name = command + "_" + slug(root)
+			chdir = command + " -chdir=" + quote(root)
+		}
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			name+"_init",
+			chdir+" init -backend=false",
+		))
+		stage.Steps = append(stage.Steps, createScriptStep(image,
+			name+"_validate",
+			chdir+" validate",
+		))
+	}
+
+	// add the linter and static analysis steps.
+	var tflintImage, tfsecImage string
+	if isContainerRuntime(pipeline) {
+		tflintImage = "ghcr.io/terraform-linters/tflint"
+		tfsecImage = "aquasec/tfsec"
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(tflintImage,
+		"tflint",
+		"tflint --init && tflint --recursive",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(tfsecImage,
+		"tfsec",
+		"tfsec .",
+	))
+
+	return nil
+}
+
+// helper function returns the root modules, which are the
+// directories with terraform configuration files that are
+// not referenced as a local module source by another
+// directory, and the required terraform version.
+func terraformRoots(fsys fs.FS) (roots []string, required string) {
+	var dirs []string
+	seen := map[string]bool{}
+	modules := map[string]bool{}
+	for _, name := range append(walk(fsys, "*.tf"), walk(fsys, "*.tofu")...) {
+		dir := path.Dir(name)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+		data, _ := read(fsys, name)
+		for _, match := range terraformModule.FindAllSubmatch(data, -1) {
+			modules[path.Join(dir, string(match[1]))] = true
+		}
+		if match := terraformRequired.FindSubmatch(data); match != nil && required == "" {
+			required = string(match[1])
+		}
+	}
+	for _, dir := range dirs {
+		if !modules[dir] {
+			roots = append(roots, dir)
+		}
+	}
+	sort.Strings(roots)
+	return roots, required
+}
+
+// helper function returns the terraform or opentofu image,
+// with the version from the version file, the .tool-versions
+// file or the required version constraint.
+func terraformImage(fsys fs.FS, command, required string) string {
+	image, versions := "hashicorp/terraform", []string{
+		readVersion(fsys, ".terraform-version"),
+		toolVersion(fsys, "terraform"),
+		required,
+	}
+	if command == "tofu" {
+		image, versions = "ghcr.io/opentofu/opentofu", []string{
+			readVersion(fsys, ".opentofu-version"),
+			toolVersion(fsys, "opentofu"),
+			required,
+		}
+	}
+	for _, v := range versions {
+		if v := minorVersion(v); v != "" {
+			return image + ":" + v
+		}
+	}
+	return image
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"io/fs"
+	"regexp"
+
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// regular expression to extract the minimum zig version
+// from the build.zig.zon file.
+var zigMinimum = regexp.MustCompile(`\.minimum_zig_version\s*=\s*"([^"]+)"`)
+
+// ConfigureZig configures a Zig step.
+func ConfigureZig(fsys fs.FS, pipeline *spec.Pipeline) error {
+	stage := pipeline.Stages[0]
+
+	// check for the build.zig file.
+	if !exists(fsys, "build.zig") {
+		return nil
+	}
+
+	// check if we should use a container-based
+	// execution environment. there is no official zig
+	// image, so the zig release published to pypi is
+	// installed in each step.
+	var image, setup string
+	command := "zig"
+	if isContainerRuntime(pipeline) {
+		image = "python:3-slim"
+		setup = "pip install ziglang && "
+		if v := zigVersion(fsys); v != "" {
+			setup = "pip install ziglang==" + v + " && "
+		}
+		command = "python -m ziglang"
+	}
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"zig_build",
+		setup+command+" build",
+	))
+
+	stage.Steps = append(stage.Steps, createScriptStep(image,
+		"zig_test",
+		setup+command+" build test",
+	))
+
+	return nil
+}
+
+// helper function returns the zig version from the
+// .zigversion file or the minimum version in the
+// build.zig.zon file.
+func zigVersion(fsys fs.FS) string {
+	if v := readVersion(fsys, ".zigversion"); v != "" {
+		return v
+	}
+	data, _ := read(fsys, "build.zig.zon")
+	if match := zigMinimum.FindSubmatch(data); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
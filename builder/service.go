@@ -0,0 +1,84 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	spec "github.com/bradrydzewski/spec/yaml"
+)
+
+// service defines a service container, such as a database,
+// that runs in the background for the duration of the stage.
+type service struct {
+	name     string            // service name, used as the hostname
+	image    string            // service image
+	env      map[string]string // service environment
+	port     string            // service port
+	username string            // service username, if any
+	password string            // service password, if any
+	database string            // environment variable to create a database, if any
+}
+
+// well-known service containers.
+var (
+	postgresService = service{
+		name:     "postgres",
+		image:    "postgres",
+		env:      map[string]string{"POSTGRES_PASSWORD": "postgres"},
+		port:     "5432",
+		username: "postgres",
+		password: "postgres",
+		database: "POSTGRES_DB",
+	}
+	mysqlService = service{
+		name:     "mysql",
+		image:    "mysql",
+		env:      map[string]string{"MYSQL_ROOT_PASSWORD": "mysql"},
+		port:     "3306",
+		username: "root",
+		password: "mysql",
+		database: "MYSQL_DATABASE",
+	}
+	redisService = service{
+		name:  "redis",
+		image: "redis",
+		port:  "6379",
+	}
+)
+
+// step returns the background step for the service.
+func (s service) step() *spec.Step {
+	return createServiceStep(s.image, s.name, s.env)
+}
+
+// withDatabase returns a copy of the service that creates
+// the named database on startup.
+func (s service) withDatabase(name string) service {
+	env := map[string]string{s.database: name}
+	for k, v := range s.env {
+		env[k] = v
+	}
+	s.env = env
+	return s
+}
+
+// url returns the connection url for the service using the
+// given scheme and path (e.g. database name).
+func (s service) url(scheme, path string) string {
+	var userinfo string
+	if s.username != "" {
+		userinfo = s.username + ":" + s.password + "@"
+	}
+	return scheme + "://" + userinfo + s.name + ":" + s.port + "/" + path
+}
@@ -0,0 +1,190 @@
+// Copyright 2022 Harness, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"strings"
+)
+
+// tomlFile provides a minimal, flattened view of a toml
+// file, keyed by table name and then by key name. Values
+// are stored in their raw, unparsed form.
+//
+// This is not a complete toml parser. It only supports the
+// subset of toml required to inspect well-known project
+// manifests (e.g. Cargo.toml, pyproject.toml).
+type tomlFile map[string]map[string]string
+
+// helper function parses the named toml file at the base
+// path.
+func readToml(fsys fs.FS, name string) (tomlFile, error) {
+	data, err := read(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return parseToml(data), nil
+}
+
+// helper function parses the toml data.
+func parseToml(data []byte) tomlFile {
+	file := tomlFile{"": {}}
+	table := ""
+
+	var key, value string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// continue reading a multi-line value until
+		// the brackets or quotes are balanced.
+		if key != "" {
+			value = value + "\n" + line
+			if !tomlComplete(value) {
+				continue
+			}
+			file[table][key] = tomlTrim(value)
+			key, value = "", ""
+			continue
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "", strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "["):
+			table = strings.Trim(tomlStrip(line), "[] ")
+			table = strings.ReplaceAll(table, `"`, "")
+			if _, ok := file[table]; !ok {
+				file[table] = map[string]string{}
+			}
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		k := strings.Trim(strings.TrimSpace(parts[0]), `"'`)
+		v := strings.TrimSpace(parts[1])
+		if !tomlComplete(v) {
+			key, value = k, v
+			continue
+		}
+		file[table][k] = tomlTrim(v)
+	}
+	return file
+}
+
+// has returns true if the named table, or any of its
+// sub-tables, exists.
+func (t tomlFile) has(table string) bool {
+	for name := range t {
+		if name == table || strings.HasPrefix(name, table+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// raw returns the raw value of the key in the named table.
+func (t tomlFile) raw(table, key string) string {
+	return t[table][key]
+}
+
+// get returns the string value of the key in the named
+// table.
+func (t tomlFile) get(table, key string) string {
+	return tomlUnquote(t[table][key])
+}
+
+// array returns the string array value of the key in the
+// named table.
+func (t tomlFile) array(table, key string) []string {
+	v := strings.TrimSpace(t[table][key])
+	if !strings.HasPrefix(v, "[") {
+		return nil
+	}
+	v = strings.TrimSuffix(strings.TrimPrefix(v, "["), "]")
+
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = tomlUnquote(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// helper function returns the value with trailing comments
+// and newlines removed.
+func tomlTrim(v string) string {
+	lines := strings.Split(v, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(tomlStrip(line))
+	}
+	return strings.TrimSpace(strings.Join(lines, " "))
+}
+
+// helper function strips a trailing comment from the line,
+// ignoring comment characters inside quoted strings.
+func tomlStrip(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// helper function returns true if the value does not span
+// additional lines.
+func tomlComplete(v string) bool {
+	if strings.Count(v, `"""`)%2 != 0 || strings.Count(v, `'''`)%2 != 0 {
+		return false
+	}
+	var depth int
+	for _, line := range strings.Split(v, "\n") {
+		var quote rune
+		for _, c := range tomlStrip(line) {
+			switch {
+			case quote != 0 && c == quote:
+				quote = 0
+			case quote == 0 && (c == '"' || c == '\''):
+				quote = c
+			case quote == 0 && (c == '[' || c == '{'):
+				depth++
+			case quote == 0 && (c == ']' || c == '}'):
+				depth--
+			}
+		}
+	}
+	return depth <= 0
+}
+
+// helper function unquotes a toml string value.
+func tomlUnquote(v string) string {
+	v = strings.TrimSpace(v)
+	v = strings.Trim(v, `"'`)
+	return strings.TrimSpace(v)
+}
@@ -15,10 +15,18 @@
 package builder
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
 
 	spec "github.com/bradrydzewski/spec/yaml"
+
+	"github.com/ghodss/yaml"
 )
 
 // helper function returns true if the files or folders
@@ -28,6 +36,44 @@ func match(fsys fs.FS, pattern string) bool {
 	return len(matches) > 0
 }
 
+// helper function returns the files or folders matching
+// the specified pattern, relative to the base path.
+func glob(fsys fs.FS, pattern string) []string {
+	matches, _ := fsys.(fs.GlobFS).Glob(pattern)
+	for i, match := range matches {
+		matches[i] = strings.TrimPrefix(match, "/")
+	}
+	return matches
+}
+
+// helper function returns the files with a base name
+// matching the specified pattern in the base path and all
+// subdirectories, in lexical order. Hidden and dependency
+// directories are skipped.
+func walk(fsys fs.FS, pattern string) []string {
+	var matches []string
+	fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			switch base := d.Name(); {
+			case name == ".":
+			case strings.HasPrefix(base, "."),
+				base == "node_modules",
+				base == "vendor":
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if ok, _ := path.Match(pattern, d.Name()); ok {
+			matches = append(matches, name)
+		}
+		return nil
+	})
+	return matches
+}
+
 // helper function returns true if the named file exists
 // at the base path.
 func exists(fsys fs.FS, name string) bool {
@@ -50,6 +96,138 @@ func unmarshal(fsys fs.FS, name string, v interface{}) error {
 	return json.Unmarshal(data, v)
 }
 
+// helper function unmarshals the named xml file at the base
+// path into the go structure.
+func unmarshalXml(fsys fs.FS, name string, v interface{}) error {
+	data, err := read(fsys, name)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// helper function unmarshals the named yaml file at the base
+// path into the go structure.
+func unmarshalYaml(fsys fs.FS, name string, v interface{}) error {
+	data, err := read(fsys, name)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, v)
+}
+
+// helper function converts the string to a lowercase
+// identifier suitable for use in a step name.
+func slug(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else if b.Len() != 0 && !strings.HasSuffix(b.String(), "_") {
+			b.WriteRune('_')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+// helper function quotes the string for use as a shell
+// argument, if the string contains whitespace or special
+// characters.
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"$&;|<>()*?`\\") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// helper function reads the version string from the first
+// non-empty, non-comment line of the named version file
+// (e.g. .python-version).
+func readVersion(fsys fs.FS, name string) string {
+	data, err := read(fsys, name)
+	if err != nil {
+		return ""
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) != 0 && line[0] != '#' {
+			return string(line)
+		}
+	}
+	return ""
+}
+
+// helper function reads the version of the named tool from
+// the asdf .tool-versions file. The first matching tool name
+// is returned.
+func toolVersion(fsys fs.FS, names ...string) string {
+	data, err := read(fsys, ".tool-versions")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, name := range names {
+			if fields[0] == name {
+				return fields[1]
+			}
+		}
+	}
+	return ""
+}
+
+// regular expression to extract the version numbers from a
+// version string or version constraint (e.g. >=3.9,<4).
+var version = regexp.MustCompile(`(\d+)(?:\.(\d+))?`)
+
+// helper function returns the major version from the
+// version string or version constraint.
+func majorVersion(s string) string {
+	if match := version.FindStringSubmatch(s); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// helper function returns the major and minor version from
+// the version string or version constraint. If the version
+// string does not include a minor version, the major version
+// is returned.
+func minorVersion(s string) string {
+	match := version.FindStringSubmatch(s)
+	switch {
+	case match == nil:
+		return ""
+	case match[2] == "":
+		return match[1]
+	default:
+		return match[1] + "." + match[2]
+	}
+}
+
+// helper function compares two major.minor version strings,
+// returning a positive number if a is greater than b.
+func compareVersion(a, b string) int {
+	as := strings.SplitN(a, ".", 2)
+	bs := strings.SplitN(b, ".", 2)
+	for i := 0; i < 2; i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
 // helper function returns true if the runtime engine is
 // kubernetes or is container-based.
 func isContainerRuntime(pipeline *spec.Pipeline) bool {
@@ -6,6 +6,8 @@ require (
 	github.com/bradrydzewski/spec v1.0.1-0.20240721144557-91d495656d51
 	github.com/ghodss/yaml v1.0.0
 	github.com/go-git/go-git/v5 v5.5.2
+	github.com/google/subcommands v1.2.0
+	github.com/r3labs/diff v1.1.0
 )
 
 require (
@@ -17,12 +19,10 @@ require (
 	github.com/emirpasic/gods v1.18.1 // indirect
 	github.com/go-git/gcfg v1.5.0 // indirect
 	github.com/go-git/go-billy/v5 v5.4.0 // indirect
-	github.com/google/subcommands v1.2.0 // indirect
 	github.com/imdario/mergo v0.3.13 // indirect
 	github.com/jbenet/go-context v0.0.0-20150711004518-d14ea06fba99 // indirect
 	github.com/kevinburke/ssh_config v1.2.0 // indirect
 	github.com/pjbgf/sha1cd v0.2.3 // indirect
-	github.com/r3labs/diff v1.1.0 // indirect
 	github.com/sergi/go-diff v1.1.0 // indirect
 	github.com/skeema/knownhosts v1.1.0 // indirect
 	github.com/xanzy/ssh-agent v0.3.3 // indirect
@@ -15,15 +15,16 @@ import (
 )
 
 type Generate struct {
-	username   string
-	password   string
-	privatekey string
+	username      string
+	password      string
+	privatekey    string
+	multiplatform bool
 }
 
 func (*Generate) Name() string     { return "generate" }
 func (*Generate) Synopsis() string { return "generate generates a pipeline" }
 func (*Generate) Usage() string {
-	return `generate [-username] [-password] <repository>
+	return `generate [-username] [-password] [-multiplatform] <repository>
 `
 }
 
@@ -31,12 +32,16 @@ func (c *Generate) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.username, "username", "", "repository username")
 	f.StringVar(&c.password, "password", "", "repository password")
 	f.StringVar(&c.privatekey, "privatekey", "", "repositroy private key")
+	f.BoolVar(&c.multiplatform, "multiplatform", false, "build multi-platform docker images")
 }
 
 func (c *Generate) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
 	// extract the repository path
 	path := f.Arg(0)
 
+	// the remote repository url, if any
+	var remote string
+
 	// if the path is a repository url,
 	// clone the rempository into a temporary
 	// directory, and then delete
@@ -59,7 +64,7 @@ func (c *Generate) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{})
 		cloner.Clone(context.Background(), params)
 
 		// change the path to the temp directory
-		path = temp
+		remote, path = path, temp
 	}
 
 	// create a chroot virtual filesystem that we
@@ -72,7 +77,11 @@ func (c *Generate) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{})
 
 	// builds the pipeline configuration based on
 	// the contents of the virtual filesystem.
-	builder := builder.New()
+	newBuilder := builder.NewRemote
+	if c.multiplatform {
+		newBuilder = builder.NewMultiPlatform
+	}
+	builder := newBuilder(remote)
 	out, err := builder.Build(chroot)
 	if err != nil {
 		fmt.Fprint(os.Stderr, err)